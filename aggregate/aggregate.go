@@ -0,0 +1,510 @@
+// Package aggregate sums metric vectors of many clients without revealing them. Each client
+// encrypts its vector bit by bit, with A proof that every bit is 0 or 1, so every value is
+// in the range of the round. An aggregator verifies the submissions and adds them with
+// PubKey.Add, and it keeps track of the registered clients that did not submit their values.
+// The committee decrypts only the final aggregate, and only if it was computed from at least
+// the minimum number of participants of the round.
+//
+// Submissions are signed by the clients with their registered keys, so the aggregator cannot
+// forge submissions to reach the minimum number of participants. The committee verifies the
+// signatures and the range proofs of every submission of an aggregate again before decrypting
+// it.
+//
+// Each holder decrypts at most one aggregate per round. Otherwise, the aggregator could ask
+// for the sums of A round with and without the values of A client, and their difference
+// would be the values of that client. Holders get the rounds from A trusted source, like the
+// organiser of the aggregations, so the aggregator cannot change the registered clients or
+// the minimum number of participants of A round.
+package aggregate
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+// digestDomain separates the signatures of submissions from other signatures.
+const digestDomain = "tcpaillier aggregate submission v1"
+
+// Round describes an aggregation. Every submission has Dimension values, each one of them
+// between 0 (inclusive) and 2^Bits (exclusive). Clients has the registered clients and their
+// public keys. An aggregate is decrypted only if it has the values of at least
+// MinParticipants clients.
+type Round struct {
+	ID              string
+	Dimension       int
+	Bits            int
+	MinParticipants int
+	Clients         map[string]ed25519.PublicKey
+}
+
+// Value is A value encrypted bit by bit, from the least significant one, with A proof that
+// each encrypted bit is 0 or 1.
+type Value struct {
+	Bits   []*big.Int
+	Proofs []*tcpaillier.BitZK
+}
+
+// Submission is the encrypted vector of A client, signed with its key.
+type Submission struct {
+	RoundID   string
+	Client    string
+	Values    []*Value
+	Signature []byte
+}
+
+// Aggregate is the encrypted sum of the vectors of the submissions of A round.
+type Aggregate struct {
+	RoundID     string
+	Fingerprint tcpaillier.Fingerprint
+	Sums        []*big.Int
+	Submissions []*Submission
+}
+
+// Aggregator verifies and adds the submissions of A round. It can be used concurrently.
+type Aggregator struct {
+	pk          *tcpaillier.PubKey
+	round       *Round
+	mutex       sync.Mutex
+	sums        []*big.Int
+	submissions map[string]*Submission
+}
+
+// Holder decrypts partially the aggregates of A set of trusted rounds with A key share, at
+// most once per round. It can be used concurrently.
+type Holder struct {
+	signer    tcpaillier.ShareSigner
+	rounds    map[string]*Round
+	mutex     sync.Mutex
+	decrypted map[string]bool
+}
+
+// HolderShares are the decryption shares of the sums of an aggregate sent by A key share
+// holder, with their proofs, in the same order as the sums.
+type HolderShares struct {
+	Index  uint8
+	Shares []*tcpaillier.DecryptionShare
+	Proofs []*tcpaillier.DecryptShareZK
+}
+
+// Validate checks that the round can be aggregated with the public key: the sum of the
+// maximum values of every client must not overflow N^S.
+func (round *Round) Validate(pk *tcpaillier.PubKey) error {
+	if round.Dimension < 1 {
+		return fmt.Errorf("dimension should be at least 1, but it is %d", round.Dimension)
+	}
+	if round.Bits < 1 || round.Bits > 64 {
+		return fmt.Errorf("bits should be between 1 and 64, but they are %d", round.Bits)
+	}
+	if round.MinParticipants < 1 || round.MinParticipants > len(round.Clients) {
+		return fmt.Errorf("minimum participants should be between 1 and %d, but it is %d", len(round.Clients), round.MinParticipants)
+	}
+	for client, key := range round.Clients {
+		if len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("client %q has an invalid public key", client)
+		}
+	}
+	max := new(big.Int).Lsh(big.NewInt(1), uint(round.Bits))
+	max.Sub(max, big.NewInt(1))
+	max.Mul(max, big.NewInt(int64(len(round.Clients))))
+	if max.Cmp(pk.Cache().NToS) >= 0 {
+		return fmt.Errorf("the sum of %d values of %d bits would overflow N^S", len(round.Clients), round.Bits)
+	}
+	return nil
+}
+
+// Submit encrypts the values of A client for the round and signs them with its key.
+func Submit(pk *tcpaillier.PubKey, round *Round, client string, key ed25519.PrivateKey, values []uint64) (sub *Submission, err error) {
+	if len(values) != round.Dimension {
+		err = fmt.Errorf("there should be %d values, but there are %d", round.Dimension, len(values))
+		return
+	}
+	encrypted := make([]*Value, len(values))
+	for i, val := range values {
+		if round.Bits < 64 && val>>uint(round.Bits) != 0 {
+			err = fmt.Errorf("value %d does not fit on %d bits", i, round.Bits)
+			return
+		}
+		v := &Value{
+			Bits:   make([]*big.Int, round.Bits),
+			Proofs: make([]*tcpaillier.BitZK, round.Bits),
+		}
+		for j := 0; j < round.Bits; j++ {
+			bit := big.NewInt(int64((val >> uint(j)) & 1))
+			if v.Bits[j], v.Proofs[j], err = pk.EncryptBitWithProof(bit); err != nil {
+				return
+			}
+		}
+		encrypted[i] = v
+	}
+	sub = &Submission{
+		RoundID: round.ID,
+		Client:  client,
+		Values:  encrypted,
+	}
+	sub.Signature = ed25519.Sign(key, sub.digest())
+	return
+}
+
+// Verify checks that the submission is signed by A registered client of the round, and that
+// every value of it is in the range of the round. It returns the encrypted values.
+func (round *Round) Verify(pk *tcpaillier.PubKey, sub *Submission) (cs []*big.Int, err error) {
+	if sub == nil {
+		err = fmt.Errorf("submission is not defined")
+		return
+	}
+	if sub.RoundID != round.ID {
+		err = fmt.Errorf("submission of %q is for round %q, not for %q", sub.Client, sub.RoundID, round.ID)
+		return
+	}
+	key, ok := round.Clients[sub.Client]
+	if !ok {
+		err = fmt.Errorf("client %q is not registered", sub.Client)
+		return
+	}
+	if !ed25519.Verify(key, sub.digest(), sub.Signature) {
+		err = fmt.Errorf("submission of %q has an invalid signature", sub.Client)
+		return
+	}
+	if len(sub.Values) != round.Dimension {
+		err = fmt.Errorf("submission of %q should have %d values, but it has %d", sub.Client, round.Dimension, len(sub.Values))
+		return
+	}
+	cs = make([]*big.Int, len(sub.Values))
+	for i, v := range sub.Values {
+		if v == nil || len(v.Bits) != round.Bits || len(v.Proofs) != round.Bits {
+			err = fmt.Errorf("value %d of %q should have %d bits with their proofs", i, sub.Client, round.Bits)
+			return
+		}
+		for j, bit := range v.Bits {
+			if err = v.Proofs[j].Verify(pk, bit); err != nil {
+				err = fmt.Errorf("bit %d of value %d of %q: %w", j, i, sub.Client, err)
+				return
+			}
+		}
+		cs[i] = v.join(pk)
+	}
+	return
+}
+
+// NewAggregator returns an aggregator for the round.
+func NewAggregator(pk *tcpaillier.PubKey, round *Round) (ag *Aggregator, err error) {
+	if err = round.Validate(pk); err != nil {
+		return
+	}
+	ag = &Aggregator{
+		pk:          pk,
+		round:       round,
+		submissions: make(map[string]*Submission),
+	}
+	return
+}
+
+// Add verifies A submission and adds its values to the sums. It returns an error if the
+// submission is invalid, or if its client already submitted its values.
+func (ag *Aggregator) Add(sub *Submission) error {
+	cs, err := ag.round.Verify(ag.pk, sub)
+	if err != nil {
+		return err
+	}
+	ag.mutex.Lock()
+	defer ag.mutex.Unlock()
+	if _, ok := ag.submissions[sub.Client]; ok {
+		return fmt.Errorf("client %q already submitted its values", sub.Client)
+	}
+	if ag.sums == nil {
+		ag.sums = cs
+	} else {
+		for i, c := range cs {
+			if ag.sums[i], err = ag.pk.Add(ag.sums[i], c); err != nil {
+				return err
+			}
+		}
+	}
+	ag.submissions[sub.Client] = sub
+	return nil
+}
+
+// Contributors returns the clients that submitted their values, sorted.
+func (ag *Aggregator) Contributors() []string {
+	ag.mutex.Lock()
+	defer ag.mutex.Unlock()
+	clients := make([]string, 0, len(ag.submissions))
+	for client := range ag.submissions {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	return clients
+}
+
+// Dropouts returns the registered clients that did not submit their values, sorted.
+func (ag *Aggregator) Dropouts() []string {
+	ag.mutex.Lock()
+	defer ag.mutex.Unlock()
+	var clients []string
+	for client := range ag.round.Clients {
+		if _, ok := ag.submissions[client]; !ok {
+			clients = append(clients, client)
+		}
+	}
+	sort.Strings(clients)
+	return clients
+}
+
+// Aggregate returns the encrypted sums of the submissions. It returns an error if there are
+// less submissions than the minimum number of participants of the round.
+func (ag *Aggregator) Aggregate() (agg *Aggregate, err error) {
+	ag.mutex.Lock()
+	defer ag.mutex.Unlock()
+	if len(ag.submissions) < ag.round.MinParticipants {
+		err = fmt.Errorf("round %q has %d participants, but it needs at least %d", ag.round.ID, len(ag.submissions), ag.round.MinParticipants)
+		return
+	}
+	clients := make([]string, 0, len(ag.submissions))
+	for client := range ag.submissions {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	agg = &Aggregate{
+		RoundID:     ag.round.ID,
+		Fingerprint: ag.pk.Fingerprint(),
+		Sums:        append([]*big.Int{}, ag.sums...),
+		Submissions: make([]*Submission, len(clients)),
+	}
+	for i, client := range clients {
+		agg.Submissions[i] = ag.submissions[client]
+	}
+	return
+}
+
+// Verify checks that the aggregate is the sum of valid submissions of different clients, and
+// that there are at least the minimum number of participants of the round.
+func (agg *Aggregate) Verify(pk *tcpaillier.PubKey, round *Round) error {
+	if err := round.Validate(pk); err != nil {
+		return err
+	}
+	if agg.RoundID != round.ID {
+		return fmt.Errorf("aggregate is for round %q, not for %q", agg.RoundID, round.ID)
+	}
+	if agg.Fingerprint != pk.Fingerprint() {
+		return fmt.Errorf("aggregate: %w", tcpaillier.ErrFingerprintMismatch)
+	}
+	if len(agg.Submissions) < round.MinParticipants {
+		return fmt.Errorf("aggregate has %d participants, but it needs at least %d", len(agg.Submissions), round.MinParticipants)
+	}
+	if len(agg.Sums) != round.Dimension {
+		return fmt.Errorf("aggregate should have %d sums, but it has %d", round.Dimension, len(agg.Sums))
+	}
+	clients := make(map[string]bool)
+	var sums []*big.Int
+	for _, sub := range agg.Submissions {
+		cs, err := round.Verify(pk, sub)
+		if err != nil {
+			return err
+		}
+		if clients[sub.Client] {
+			return fmt.Errorf("client %q has more than one submission", sub.Client)
+		}
+		clients[sub.Client] = true
+		if sums == nil {
+			sums = cs
+			continue
+		}
+		for i, c := range cs {
+			if sums[i], err = pk.Add(sums[i], c); err != nil {
+				return err
+			}
+		}
+	}
+	for i, sum := range sums {
+		if agg.Sums[i] == nil || sum.Cmp(agg.Sums[i]) != 0 {
+			return fmt.Errorf("sum %d is not the sum of the submissions", i)
+		}
+	}
+	return nil
+}
+
+// NewHolder returns A holder with the key share signer, that decrypts only aggregates of the
+// rounds provided. The rounds must come from A trusted source, like the organiser of the
+// aggregations, and not from the aggregator. Decrypted are the identifiers of the rounds the
+// holder already decrypted, as returned by Holder.Decrypted, so they are kept when the
+// holder is restarted.
+func NewHolder(signer tcpaillier.ShareSigner, rounds []*Round, decrypted ...string) (h *Holder, err error) {
+	h = &Holder{
+		signer:    signer,
+		rounds:    make(map[string]*Round),
+		decrypted: make(map[string]bool),
+	}
+	for _, round := range rounds {
+		if round == nil {
+			err = fmt.Errorf("round is not defined")
+			return
+		}
+		if err = round.Validate(signer.PublicKey()); err != nil {
+			err = fmt.Errorf("round %q: %w", round.ID, err)
+			return
+		}
+		if _, ok := h.rounds[round.ID]; ok {
+			err = fmt.Errorf("round %q is repeated", round.ID)
+			return
+		}
+		// The round is copied, so it cannot be modified after it is validated.
+		trusted := *round
+		trusted.Clients = make(map[string]ed25519.PublicKey, len(round.Clients))
+		for client, key := range round.Clients {
+			trusted.Clients[client] = key
+		}
+		h.rounds[round.ID] = &trusted
+	}
+	for _, id := range decrypted {
+		h.decrypted[id] = true
+	}
+	return
+}
+
+// Decrypted returns the identifiers of the rounds the holder already decrypted, sorted.
+func (h *Holder) Decrypted() []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	ids := make([]string, 0, len(h.decrypted))
+	for id := range h.decrypted {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// PartialDecrypt verifies the aggregate with its trusted round, and decrypts partially its
+// sums with the key share of the holder. It refuses to decrypt an aggregate of an unknown
+// round, with less than the minimum number of participants of the round, or of A round the
+// holder already decrypted.
+func (h *Holder) PartialDecrypt(agg *Aggregate) (hs *HolderShares, err error) {
+	if agg == nil {
+		err = fmt.Errorf("refusing to decrypt: aggregate is not defined")
+		return
+	}
+	round, ok := h.rounds[agg.RoundID]
+	if !ok {
+		err = fmt.Errorf("refusing to decrypt: round %q is unknown", agg.RoundID)
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.decrypted[round.ID] {
+		err = fmt.Errorf("refusing to decrypt: round %q was already decrypted", round.ID)
+		return
+	}
+	if err = agg.Verify(h.signer.PublicKey(), round); err != nil {
+		err = fmt.Errorf("refusing to decrypt: %w", err)
+		return
+	}
+	hs = &HolderShares{
+		Index:  h.signer.ShareIndex(),
+		Shares: make([]*tcpaillier.DecryptionShare, len(agg.Sums)),
+		Proofs: make([]*tcpaillier.DecryptShareZK, len(agg.Sums)),
+	}
+	for i, sum := range agg.Sums {
+		if hs.Shares[i], err = h.signer.PartialDecrypt(sum); err != nil {
+			return
+		}
+		if hs.Proofs[i], err = h.signer.PartialDecryptProof(sum, hs.Shares[i]); err != nil {
+			return
+		}
+	}
+	h.decrypted[round.ID] = true
+	return
+}
+
+// Combine verifies the shares of the holders and combines them to decrypt the sums of the
+// aggregate. The holders with invalid shares are skipped.
+func (agg *Aggregate) Combine(pk *tcpaillier.PubKey, holders ...*HolderShares) (sums []*big.Int, err error) {
+	valid := make([][]*tcpaillier.DecryptionShare, len(agg.Sums))
+	used := make(map[uint8]bool)
+	count := 0
+	for _, hs := range holders {
+		if count == int(pk.K) {
+			break
+		}
+		if hs == nil || used[hs.Index] || !hs.valid(pk, agg.Sums) {
+			continue
+		}
+		used[hs.Index] = true
+		count++
+		for i, share := range hs.Shares {
+			valid[i] = append(valid[i], share)
+		}
+	}
+	if count < int(pk.K) {
+		err = &tcpaillier.InsufficientSharesError{Have: count, Need: int(pk.K)}
+		return
+	}
+	sums = make([]*big.Int, len(agg.Sums))
+	for i, shares := range valid {
+		if sums[i], err = pk.CombineShares(shares...); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// valid returns true if the holder has A valid share for every sum.
+func (hs *HolderShares) valid(pk *tcpaillier.PubKey, sums []*big.Int) bool {
+	if len(hs.Shares) != len(sums) || len(hs.Proofs) != len(sums) {
+		return false
+	}
+	for i, share := range hs.Shares {
+		if share == nil || share.Index != hs.Index || hs.Proofs[i].Verify(pk, sums[i], share) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// join returns the encryption of the value from the encryptions of its bits, computing
+// the product of Bits[j]^(2^j) with Horner's method.
+func (v *Value) join(pk *tcpaillier.PubKey) *big.Int {
+	nToSPlusOne := pk.Cache().NToSPlusOne
+	c := new(big.Int).Set(v.Bits[len(v.Bits)-1])
+	for j := len(v.Bits) - 2; j >= 0; j-- {
+		c.Mul(c, c).Mul(c, v.Bits[j]).Mod(c, nToSPlusOne)
+	}
+	return c
+}
+
+// digest returns the hash signed by the client of the submission.
+func (sub *Submission) digest() []byte {
+	hash := sha256.New()
+	length := make([]byte, 4)
+	write := func(b []byte) {
+		binary.BigEndian.PutUint32(length, uint32(len(b)))
+		hash.Write(length)
+		hash.Write(b)
+	}
+	write([]byte(digestDomain))
+	write([]byte(sub.RoundID))
+	write([]byte(sub.Client))
+	binary.BigEndian.PutUint32(length, uint32(len(sub.Values)))
+	hash.Write(length)
+	for _, v := range sub.Values {
+		if v == nil {
+			write(nil)
+			continue
+		}
+		binary.BigEndian.PutUint32(length, uint32(len(v.Bits)))
+		hash.Write(length)
+		for _, bit := range v.Bits {
+			if bit == nil {
+				write(nil)
+			} else {
+				write(bit.Bytes())
+			}
+		}
+	}
+	return hash.Sum(nil)
+}
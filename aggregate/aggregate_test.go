@@ -0,0 +1,250 @@
+package aggregate_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/aggregate"
+)
+
+const k = 2
+const l = 3
+const s = 1
+
+const bitSize = 256
+
+// newRound returns A round with the number of clients provided and their private keys.
+func newRound(t *testing.T, clients, minParticipants int) (*aggregate.Round, map[string]ed25519.PrivateKey) {
+	round := &aggregate.Round{
+		ID:              "round-1",
+		Dimension:       2,
+		Bits:            4,
+		MinParticipants: minParticipants,
+		Clients:         make(map[string]ed25519.PublicKey),
+	}
+	keys := make(map[string]ed25519.PrivateKey)
+	for i := 0; i < clients; i++ {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		name := fmt.Sprintf("client-%d", i+1)
+		round.Clients[name] = pub
+		keys[name] = priv
+	}
+	return round, keys
+}
+
+func TestAggregator(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	round, keys := newRound(t, 4, 3)
+	ag, err := aggregate.NewAggregator(pk, round)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	values := map[string][]uint64{
+		"client-1": {1, 15},
+		"client-2": {0, 7},
+		"client-4": {9, 15},
+	}
+	for client, vals := range values {
+		sub, err := aggregate.Submit(pk, round, client, keys[client], vals)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if err := ag.Add(sub); err != nil {
+			t.Fatalf("%v", err)
+		}
+		if err := ag.Add(sub); err == nil {
+			t.Errorf("repeated submission of %s should be rejected", client)
+		}
+	}
+	if dropouts := ag.Dropouts(); len(dropouts) != 1 || dropouts[0] != "client-3" {
+		t.Errorf("dropouts should be [client-3], but they are %v", dropouts)
+	}
+	if contributors := ag.Contributors(); len(contributors) != 3 {
+		t.Errorf("there should be 3 contributors, but there are %v", contributors)
+	}
+	agg, err := ag.Aggregate()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	holders := make([]*aggregate.HolderShares, len(shares))
+	for i, share := range shares {
+		holder, err := aggregate.NewHolder(share, []*aggregate.Round{round})
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if holders[i], err = holder.PartialDecrypt(agg); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	// The shares of the first holder are modified, so they are skipped.
+	holders[0].Shares[1].Ci.Add(holders[0].Shares[1].Ci, holders[0].Shares[1].Ci)
+	sums, err := agg.Combine(pk, holders...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for i, expected := range []int64{10, 37} {
+		if sums[i].Int64() != expected {
+			t.Errorf("sum %d should be %d, but it is %s", i, expected, sums[i])
+		}
+	}
+}
+
+func TestAggregator_minParticipants(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	round, keys := newRound(t, 4, 3)
+	ag, err := aggregate.NewAggregator(pk, round)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var subs []*aggregate.Submission
+	for _, client := range []string{"client-1", "client-2"} {
+		sub, err := aggregate.Submit(pk, round, client, keys[client], []uint64{3, 4})
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if err := ag.Add(sub); err != nil {
+			t.Fatalf("%v", err)
+		}
+		subs = append(subs, sub)
+	}
+	if _, err := ag.Aggregate(); err == nil {
+		t.Errorf("aggregate with 2 participants should not be returned")
+	}
+
+	// A dishonest aggregator repeats A submission to reach the minimum.
+	agg := &aggregate.Aggregate{
+		RoundID:     round.ID,
+		Fingerprint: pk.Fingerprint(),
+		Sums:        []*big.Int{subs[0].Values[0].Bits[0], subs[0].Values[1].Bits[0]},
+		Submissions: append(subs, subs[0]),
+	}
+	holder, err := aggregate.NewHolder(shares[0], []*aggregate.Round{round})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := holder.PartialDecrypt(agg); err == nil || !strings.Contains(err.Error(), "more than one submission") {
+		t.Errorf("holders should not decrypt an aggregate with repeated submissions, but the error is %v", err)
+	}
+
+	// A dishonest aggregator forges the submission of A client that dropped out.
+	_, forger, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	forged, err := aggregate.Submit(pk, round, "client-3", forger, []uint64{0, 0})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := ag.Add(forged); err == nil {
+		t.Errorf("submission with an invalid signature should be rejected")
+	}
+
+	if _, err := aggregate.Submit(pk, round, "client-3", keys["client-3"], []uint64{16, 0}); err == nil {
+		t.Errorf("values out of range should not be submitted")
+	}
+}
+
+func TestHolder_PartialDecrypt_once(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	round, keys := newRound(t, 4, 2)
+	values := map[string][]uint64{
+		"client-1": {1, 2},
+		"client-2": {3, 4},
+		"client-3": {5, 6},
+	}
+	// The aggregator computes the sums with and without the values of client-1.
+	aggregates := make([]*aggregate.Aggregate, 2)
+	for i, clients := range [][]string{{"client-1", "client-2", "client-3"}, {"client-2", "client-3"}} {
+		ag, err := aggregate.NewAggregator(pk, round)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		for _, client := range clients {
+			sub, err := aggregate.Submit(pk, round, client, keys[client], values[client])
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if err := ag.Add(sub); err != nil {
+				t.Fatalf("%v", err)
+			}
+		}
+		if aggregates[i], err = ag.Aggregate(); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	holder, err := aggregate.NewHolder(shares[0], []*aggregate.Round{round})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := holder.PartialDecrypt(aggregates[0]); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := holder.PartialDecrypt(aggregates[1]); err == nil || !strings.Contains(err.Error(), "already decrypted") {
+		t.Errorf("holder should not decrypt A second aggregate of the round, but the error is %v", err)
+	}
+	// The decrypted rounds are kept when the holder is restarted.
+	restarted, err := aggregate.NewHolder(shares[0], []*aggregate.Round{round}, holder.Decrypted()...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := restarted.PartialDecrypt(aggregates[1]); err == nil || !strings.Contains(err.Error(), "already decrypted") {
+		t.Errorf("restarted holder should not decrypt A second aggregate of the round, but the error is %v", err)
+	}
+}
+
+func TestHolder_PartialDecrypt_changedRound(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	round, keys := newRound(t, 4, 3)
+	holder, err := aggregate.NewHolder(shares[0], []*aggregate.Round{round})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// A dishonest aggregator registers only the victim in the round, so its aggregate has
+	// the values of the victim alone.
+	changed := *round
+	changed.Clients = map[string]ed25519.PublicKey{"client-1": round.Clients["client-1"]}
+	changed.MinParticipants = 1
+	ag, err := aggregate.NewAggregator(pk, &changed)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	sub, err := aggregate.Submit(pk, &changed, "client-1", keys["client-1"], []uint64{3, 4})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := ag.Add(sub); err != nil {
+		t.Fatalf("%v", err)
+	}
+	agg, err := ag.Aggregate()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := holder.PartialDecrypt(agg); err == nil || !strings.Contains(err.Error(), "at least 3") {
+		t.Errorf("holder should not decrypt an aggregate of A changed round, but the error is %v", err)
+	}
+	changed.ID = "round-2"
+	agg.RoundID = changed.ID
+	if _, err := holder.PartialDecrypt(agg); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("holder should not decrypt an aggregate of an unknown round, but the error is %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package tcpaillier
+
+import (
+	"fmt"
+	"math/big"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// EncryptBatch encrypts A list of messages and returns their encryptions and the random
+// numbers used, with the same indexes as the messages. errs is nil if all the messages
+// were encrypted. Otherwise, it has an error on the index of each message that could
+// not be encrypted, and the remaining messages are encrypted anyway.
+func (pk *PubKey) EncryptBatch(messages []*big.Int) (cs, rs []*big.Int, errs []error) {
+	cs = make([]*big.Int, len(messages))
+	rs = make([]*big.Int, len(messages))
+	pk.Cache()
+	errs = parallel(len(messages), func(i int) (err error) {
+		cs[i], rs[i], err = pk.Encrypt(messages[i])
+		return
+	})
+	return
+}
+
+// PartialDecryptBatch decrypts partially A list of encrypted values, using only one
+// keyShare. It returns the decryption shares with the same indexes as the encrypted
+// values. errs is nil if all the values were decrypted, and it has an error on the
+// index of every value that could not be decrypted otherwise.
+func (ts *KeyShare) PartialDecryptBatch(cs []*big.Int) (dss []*DecryptionShare, errs []error) {
+	dss = make([]*DecryptionShare, len(cs))
+	ts.Cache()
+	errs = parallel(len(cs), func(i int) (err error) {
+		dss[i], err = ts.PartialDecrypt(cs[i])
+		return
+	})
+	return
+}
+
+// CombineSharesBatch joins the partial decryptions of A list of values. shares[i] are the
+// decryption shares of the i-th value, and decs[i] is its decrypted value. The shares
+// are joined using A Combiner for each set of share indexes, so the Lagrange
+// coefficients are computed only once for each one of them. errs is nil if all
+// the values were decrypted, and it has an error on the index of every value that could
+// not be decrypted otherwise.
+func (pk *PubKey) CombineSharesBatch(shares [][]*DecryptionShare) (decs []*big.Int, errs []error) {
+	decs = make([]*big.Int, len(shares))
+	pk.Cache()
+
+	selected := make([][]*DecryptionShare, len(shares))
+	combiners := make([]*Combiner, len(shares))
+	cached := make(map[string]*Combiner)
+	for i, valShares := range shares {
+		valShares, err := pk.thresholdShares(valShares)
+		if err != nil {
+			if errs == nil {
+				errs = make([]error, len(shares))
+			}
+			errs[i] = err
+			continue
+		}
+		// We sort the shares by index to use the same combiner for every set of indexes.
+		valShares = append([]*DecryptionShare{}, valShares...)
+		sort.Slice(valShares, func(a, b int) bool {
+			return valShares[a].Index < valShares[b].Index
+		})
+		indexes := make([]uint8, len(valShares))
+		for j, share := range valShares {
+			indexes[j] = share.Index
+		}
+		key := indexesKey(indexes)
+		if _, ok := cached[key]; !ok {
+			co, err := pk.NewCombiner(indexes...)
+			if err != nil {
+				if errs == nil {
+					errs = make([]error, len(shares))
+				}
+				errs[i] = err
+				continue
+			}
+			cached[key] = co
+		}
+		selected[i] = valShares
+		combiners[i] = cached[key]
+	}
+
+	combineErrs := parallel(len(shares), func(i int) (err error) {
+		if selected[i] != nil {
+			decs[i], err = combiners[i].Combine(selected[i]...)
+		}
+		return
+	})
+	for i, err := range combineErrs {
+		if err != nil {
+			if errs == nil {
+				errs = make([]error, len(shares))
+			}
+			errs[i] = err
+		}
+	}
+	return
+}
+
+// indexesKey returns A string representing A list of share indexes.
+func indexesKey(indexes []uint8) string {
+	strs := make([]string, len(indexes))
+	for i, index := range indexes {
+		strs[i] = fmt.Sprintf("%d", index)
+	}
+	return strings.Join(strs, ",")
+}
+
+// parallel executes fn(i) for i between 0 and n - 1, using A pool with as many goroutines
+// as GOMAXPROCS. It returns nil if none of the executions failed, or A list with the error
+// returned by every execution otherwise.
+func parallel(n int, fn func(i int) error) []error {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	errs := make([]error, n)
+	failed := false
+	var mutex sync.Mutex
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := fn(i); err != nil {
+					mutex.Lock()
+					errs[i] = err
+					failed = true
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if !failed {
+		return nil
+	}
+	return errs
+}
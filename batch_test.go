@@ -0,0 +1,75 @@
+package tcpaillier_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+const batchSize = 20
+
+func TestPubKey_Batch(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	msgs := make([]*big.Int, batchSize)
+	for i := range msgs {
+		msgs[i] = big.NewInt(int64(i * i))
+	}
+	cs, _, errs := pk.EncryptBatch(msgs)
+	if errs != nil {
+		t.Errorf("error encrypting batch: %v", errs)
+		return
+	}
+
+	// An invalid ciphertext should fail without failing the rest of the batch.
+	cs[3] = new(big.Int).Set(pk.Cache().NToSPlusOne)
+
+	decryptShares := make([][]*tcpaillier.DecryptionShare, batchSize)
+	for _, share := range shares {
+		dss, errs := share.PartialDecryptBatch(cs)
+		if errs == nil || errs[3] == nil {
+			t.Errorf("partial decryption of invalid ciphertext should have failed")
+			return
+		}
+		for i, ds := range dss {
+			if i == 3 {
+				continue
+			}
+			if errs[i] != nil {
+				t.Errorf("share %d is not able to decrypt partially the message %d: %v", share.Index, i, errs[i])
+				return
+			}
+			decryptShares[i] = append(decryptShares[i], ds)
+		}
+	}
+	// Reversed shares select other indexes, so two sets of coefficients are cached.
+	for i := 0; i < batchSize; i += 2 {
+		valShares := decryptShares[i]
+		for a, b := 0, len(valShares)-1; a < b; a, b = a+1, b-1 {
+			valShares[a], valShares[b] = valShares[b], valShares[a]
+		}
+	}
+
+	decs, errs := pk.CombineSharesBatch(decryptShares)
+	if errs == nil || errs[3] == nil {
+		t.Errorf("combination without shares should have failed")
+		return
+	}
+	for i, dec := range decs {
+		if i == 3 {
+			continue
+		}
+		if errs[i] != nil {
+			t.Errorf("cannot combine shares of message %d: %v", i, errs[i])
+			return
+		}
+		if dec.Cmp(msgs[i]) != 0 {
+			t.Errorf("messages are different. Decrypted is %s and original was %s.", dec, msgs[i])
+			return
+		}
+	}
+}
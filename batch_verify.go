@@ -0,0 +1,247 @@
+package tcpaillier
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// batchVerifyBits is the bit length of the random exponents used
+// to combine the proofs in A batch.
+const batchVerifyBits = 128
+
+// VerifyEncryptBatch verifies A list of encryption ZKProofs, where proofs[i] is the proof
+// of the encrypted value cs[i]. The proofs are combined using random exponents, so the
+// whole batch is checked at the cost of A few proofs. If the batch check fails, it is
+// split in halves until the invalid proofs are found. It returns the indexes of the invalid
+// proofs, or nil if all of them are valid.
+//
+// The random exponents are odd and the Jacobi symbols of both sides of each proof are
+// compared, so A single proof that holds only up to A factor of order two, like A proof
+// with the sign of Z flipped, is always flagged.
+func (pk *PubKey) VerifyEncryptBatch(proofs []*EncryptZK, cs []*big.Int) (invalid []int, err error) {
+	if len(proofs) != len(cs) {
+		err = fmt.Errorf("there are %d proofs but %d encrypted values", len(proofs), len(cs))
+		return
+	}
+	cache := pk.Cache()
+	nPlusOne := cache.NPlusOne
+	nToSPlusOne := cache.NToSPlusOne
+	nToS := cache.NToS
+
+	es := make([]*big.Int, len(proofs))
+	var pending []int
+	for i, zk := range proofs {
+		c := cs[i]
+		if zk == nil || zk.B == nil || zk.W == nil || zk.Z == nil || pk.ValidateCiphertext(c) != nil {
+			invalid = append(invalid, i)
+			continue
+		}
+		hash := sha256.New()
+		hash.Write(c.Bytes())
+		hash.Write(zk.B.Bytes())
+		es[i] = new(big.Int).SetBytes(hash.Sum(nil))
+		// (Z^(n^s) / N) = (B / N) * (c^e / N)
+		if jz := big.Jacobi(zk.Z, pk.N); jz == 0 || jz != pk.jacobiPow(zk.B, one)*pk.jacobiPow(c, es[i]) {
+			invalid = append(invalid, i)
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	// (n+1)^(sum rho*W) * (prod Z^rho)^(n^s) = prod B^rho * CAlpha^(rho*E) % n^(s+1)
+	check := func(indexes []int) bool {
+		rhos, err := batchExponents(len(indexes))
+		if err != nil {
+			return false
+		}
+		sumW := new(big.Int)
+		zs := make([]*big.Int, len(indexes))
+		rightBases := make([]*big.Int, 0, 2*len(indexes))
+		rightExps := make([]*big.Int, 0, 2*len(indexes))
+		for j, i := range indexes {
+			zk, rho := proofs[i], rhos[j]
+			sumW.Add(sumW, new(big.Int).Mul(rho, zk.W))
+			zs[j] = zk.Z
+			rightBases = append(rightBases, zk.B, cs[i])
+			rightExps = append(rightExps, rho, new(big.Int).Mul(rho, es[i]))
+		}
+		zToRho := multiExp(zs, rhos, nToSPlusOne)
+		right := multiExp(rightBases, rightExps, nToSPlusOne)
+		if zToRho == nil || right == nil {
+			return false
+		}
+		left := new(big.Int).Exp(nPlusOne, sumW, nToSPlusOne)
+		left.Mul(left, zToRho.Exp(zToRho, nToS, nToSPlusOne)).Mod(left, nToSPlusOne)
+		return left.Cmp(right) == 0
+	}
+	single := func(i int) bool {
+		return proofs[i].Verify(pk, cs[i]) == nil
+	}
+	invalid = append(invalid, bisect(pending, check, single)...)
+	return sortedOrNil(invalid), nil
+}
+
+// VerifyDecryptShareBatch verifies A list of decryption share ZKProofs, where proofs[i] is
+// the proof of the decryption share shares[i] of the encrypted value cs[i]. The proofs are
+// combined using random exponents, so the whole batch is checked at the cost of A few proofs.
+// If the batch check fails, it is split in halves until the invalid proofs are found. It
+// returns the indexes of the invalid proofs, or nil if all of them are valid. The proofs
+// without commitments are verified one by one.
+//
+// The random exponents are odd and the Jacobi symbols of both sides of each proof are
+// compared, so A single proof that holds only up to A factor of order two is always flagged.
+func (pk *PubKey) VerifyDecryptShareBatch(proofs []*DecryptShareZK, cs []*big.Int, shares []*DecryptionShare) (invalid []int, err error) {
+	if len(proofs) != len(cs) || len(proofs) != len(shares) {
+		err = fmt.Errorf("there are %d proofs, %d encrypted values and %d decryption shares", len(proofs), len(cs), len(shares))
+		return
+	}
+	cache := pk.Cache()
+	nToSPlusOne := cache.NToSPlusOne
+
+	fp := pk.Fingerprint()
+
+	cTo4s := make([]*big.Int, len(proofs))
+	var pending []int
+	for i, zk := range proofs {
+		c, ds := cs[i], shares[i]
+		if zk == nil || zk.Z == nil || zk.E == nil || zk.V == nil || zk.Vi == nil || ds == nil {
+			invalid = append(invalid, i)
+			continue
+		}
+		if checkFingerprint("share", ds.Fingerprint, fp) != nil {
+			invalid = append(invalid, i)
+			continue
+		}
+		if pk.ValidateCiphertext(c) != nil || pk.ValidateCiphertext(ds.Ci) != nil {
+			invalid = append(invalid, i)
+			continue
+		}
+		if zk.A == nil || zk.B == nil {
+			if zk.Verify(pk, c, ds) != nil {
+				invalid = append(invalid, i)
+			}
+			continue
+		}
+		if ds.Index < 1 || ds.Index > pk.L || zk.V.Cmp(pk.V) != 0 || zk.Vi.Cmp(pk.Vi[ds.Index-1]) != 0 {
+			invalid = append(invalid, i)
+			continue
+		}
+		cTo4 := new(big.Int).Exp(c, big.NewInt(4), nToSPlusOne)
+		ciTo2 := new(big.Int).Exp(ds.Ci, two, nToSPlusOne)
+		hash := sha256.New()
+		hash.Write(zk.A.Bytes())
+		hash.Write(zk.B.Bytes())
+		hash.Write(cTo4.Bytes())
+		hash.Write(ciTo2.Bytes())
+		e := new(big.Int).SetBytes(hash.Sum(nil))
+		if e.Cmp(zk.E) != 0 {
+			invalid = append(invalid, i)
+			continue
+		}
+		// (A / N) = ((c^4)^Z / N) * (Ci^(-2E) / N) = 1
+		// (B / N) = (V^Z / N) * (Vi^(-E) / N)
+		if big.Jacobi(zk.A, pk.N) != 1 || big.Jacobi(zk.B, pk.N) != pk.jacobiPow(zk.V, zk.Z)*pk.jacobiPow(zk.Vi, zk.E) {
+			invalid = append(invalid, i)
+			continue
+		}
+		cTo4s[i] = cTo4
+		pending = append(pending, i)
+	}
+
+	// prod (c^4)^(rho*Z) * A^(-rho) * Ci^(-2*rho*E) = 1 % n^(s+1)
+	// V^(sum rho*Z) * prod B^(-rho) * Vi^(-rho*E) = 1 % n^(s+1)
+	check := func(indexes []int) bool {
+		rhos, err := batchExponents(len(indexes))
+		if err != nil {
+			return false
+		}
+		bases := make([]*big.Int, 0, 3*len(indexes))
+		exps := make([]*big.Int, 0, 3*len(indexes))
+		sumZ := new(big.Int)
+		vBases := []*big.Int{pk.V}
+		vExps := []*big.Int{sumZ}
+		viExps := make(map[uint8]*big.Int)
+		for j, i := range indexes {
+			zk, ds, rho := proofs[i], shares[i], rhos[j]
+			rhoE := new(big.Int).Mul(rho, zk.E)
+			bases = append(bases, cTo4s[i], zk.A, ds.Ci)
+			exps = append(exps,
+				new(big.Int).Mul(rho, zk.Z),
+				new(big.Int).Neg(rho),
+				new(big.Int).Mul(rhoE, big.NewInt(-2)))
+			sumZ.Add(sumZ, new(big.Int).Mul(rho, zk.Z))
+			vBases = append(vBases, zk.B)
+			vExps = append(vExps, new(big.Int).Neg(rho))
+			if _, ok := viExps[ds.Index]; !ok {
+				viExps[ds.Index] = new(big.Int)
+			}
+			viExps[ds.Index].Sub(viExps[ds.Index], rhoE)
+		}
+		for index, exp := range viExps {
+			vBases = append(vBases, pk.Vi[index-1])
+			vExps = append(vExps, exp)
+		}
+		first := multiExp(bases, exps, nToSPlusOne)
+		second := multiExp(vBases, vExps, nToSPlusOne)
+		return first != nil && second != nil && first.Cmp(one) == 0 && second.Cmp(one) == 0
+	}
+	single := func(i int) bool {
+		return proofs[i].Verify(pk, cs[i], shares[i]) == nil
+	}
+	invalid = append(invalid, bisect(pending, check, single)...)
+	return sortedOrNil(invalid), nil
+}
+
+// bisect returns the indexes that are not valid. If check fails for A list of indexes,
+// it is split in halves until the failing ones are found. Lists of one index are checked
+// with single.
+func bisect(indexes []int, check func([]int) bool, single func(int) bool) []int {
+	switch len(indexes) {
+	case 0:
+		return nil
+	case 1:
+		if single(indexes[0]) {
+			return nil
+		}
+		return []int{indexes[0]}
+	}
+	if check(indexes) {
+		return nil
+	}
+	half := len(indexes) / 2
+	return append(bisect(indexes[:half], check, single), bisect(indexes[half:], check, single)...)
+}
+
+// batchExponents returns n random exponents used to combine the proofs of A batch. The
+// exponents are odd, so A factor of order two is not cancelled by its exponent.
+func batchExponents(n int) ([]*big.Int, error) {
+	rhos := make([]*big.Int, n)
+	for i := range rhos {
+		rho, err := RandomInt(batchVerifyBits)
+		if err != nil {
+			return nil, err
+		}
+		rhos[i] = rho.SetBit(rho, 0, 1)
+	}
+	return rhos, nil
+}
+
+// jacobiPow returns the Jacobi symbol of x^e modulo N.
+func (pk *PubKey) jacobiPow(x, e *big.Int) int {
+	j := big.Jacobi(x, pk.N)
+	if j != 0 && e.Bit(0) == 0 {
+		return 1
+	}
+	return j
+}
+
+// sortedOrNil returns the list of indexes sorted, or nil if it is empty.
+func sortedOrNil(indexes []int) []int {
+	if len(indexes) == 0 {
+		return nil
+	}
+	sort.Ints(indexes)
+	return indexes
+}
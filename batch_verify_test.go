@@ -0,0 +1,118 @@
+package tcpaillier_test
+
+import (
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+func TestPubKey_VerifyEncryptBatch(t *testing.T) {
+	_, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	cs := make([]*big.Int, batchSize)
+	proofs := make([]*tcpaillier.EncryptZK, batchSize)
+	for i := range cs {
+		cs[i], proofs[i], err = pk.EncryptWithProof(big.NewInt(int64(i)))
+		if err != nil {
+			t.Errorf("%v", err)
+			return
+		}
+	}
+	invalid, err := pk.VerifyEncryptBatch(proofs, cs)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if invalid != nil {
+		t.Errorf("valid proofs %v were rejected", invalid)
+		return
+	}
+	proofs[4].W = new(big.Int).Add(proofs[4].W, big.NewInt(1))
+	// A proof that holds only up to A factor of -1
+	proofs[7].Z = new(big.Int).Sub(pk.Cache().NToSPlusOne, proofs[7].Z)
+	cs[13] = cs[12]
+	invalid, err = pk.VerifyEncryptBatch(proofs, cs)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if len(invalid) != 3 || invalid[0] != 4 || invalid[1] != 7 || invalid[2] != 13 {
+		t.Errorf("invalid proofs should have been [4 7 13], but they are %v", invalid)
+	}
+}
+
+func TestPubKey_VerifyDecryptShareBatch(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	var cs []*big.Int
+	var decryptShares []*tcpaillier.DecryptionShare
+	var proofs []*tcpaillier.DecryptShareZK
+	for i := 0; i < 3; i++ {
+		encrypted, _, err := pk.Encrypt(big.NewInt(int64(i)))
+		if err != nil {
+			t.Errorf("%v", err)
+			return
+		}
+		for _, share := range shares {
+			ds, zk, err := share.PartialDecryptWithProof(encrypted)
+			if err != nil {
+				t.Errorf("%v", err)
+				return
+			}
+			cs = append(cs, encrypted)
+			decryptShares = append(decryptShares, ds)
+			proofs = append(proofs, zk)
+		}
+	}
+	invalid, err := pk.VerifyDecryptShareBatch(proofs, cs, decryptShares)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if invalid != nil {
+		t.Errorf("valid proofs %v were rejected", invalid)
+		return
+	}
+	// A share with a wrong decryption
+	decryptShares[7].Ci = new(big.Int).Add(decryptShares[7].Ci, big.NewInt(1))
+	// A proof from another share
+	proofs[21] = proofs[22]
+	// A proof without commitments
+	proofs[25].A, proofs[25].B = nil, nil
+	// A proof with the sign of A flipped, that holds only up to A factor of -1
+	flipCommitment(pk, shares[10%len(shares)], proofs[10], cs[10], decryptShares[10])
+	invalid, err = pk.VerifyDecryptShareBatch(proofs, cs, decryptShares)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if len(invalid) != 3 || invalid[0] != 7 || invalid[1] != 10 || invalid[2] != 21 {
+		t.Errorf("invalid proofs should have been [7 10 21], but they are %v", invalid)
+	}
+}
+
+// flipCommitment flips the sign of the commitment A of A decryption share proof, and
+// computes the challenge and the response again with the key share, so
+// (c^4)^Z = -A * Ci^(2E) and V^Z = B * Vi^E.
+func flipCommitment(pk *tcpaillier.PubKey, share *tcpaillier.KeyShare, zk *tcpaillier.DecryptShareZK, c *big.Int, ds *tcpaillier.DecryptionShare) {
+	nToSPlusOne := pk.Cache().NToSPlusOne
+	zk.A = new(big.Int).Sub(nToSPlusOne, zk.A)
+	hash := sha256.New()
+	hash.Write(zk.A.Bytes())
+	hash.Write(zk.B.Bytes())
+	hash.Write(new(big.Int).Exp(c, big.NewInt(4), nToSPlusOne).Bytes())
+	hash.Write(new(big.Int).Exp(ds.Ci, big.NewInt(2), nToSPlusOne).Bytes())
+	e := new(big.Int).SetBytes(hash.Sum(nil))
+	diff := new(big.Int).Sub(e, zk.E)
+	diff.Mul(diff, pk.Delta).Mul(diff, share.Si)
+	zk.Z = new(big.Int).Add(zk.Z, diff)
+	zk.E = e
+}
@@ -0,0 +1,98 @@
+// Command tcpaillier-node serves partial decryption requests with A key share
+// stored on disk.
+//
+// Usage:
+//
+//	tcpaillier-node -share share.json -clients clients.json [-addr :8080]
+//	tcpaillier-node -sealed share.sealed.json -clients clients.json [-addr :8080]
+//
+// The share file contains A JSON serialized KeyShare, and the clients file contains
+// A JSON list of node.ClientPolicy values. A sealed share file is A keystore.Sealed
+// key share, opened with the passphrase on the TCPAILLIER_PASSPHRASE environment
+// variable only while each request is answered.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/keystore"
+	"github.com/niclabs/tcpaillier/node"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	sharePath := flag.String("share", "", "path of the key share file")
+	sealedPath := flag.String("sealed", "", "path of the sealed key share file")
+	clientsPath := flag.String("clients", "", "path of the client policies file")
+	certPath := flag.String("cert", "", "path of the TLS certificate file")
+	keyPath := flag.String("key", "", "path of the TLS key file")
+	flag.Parse()
+
+	srv, err := newServer(*sharePath, *sealedPath, *clientsPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tcpaillier-node: %v\n", err)
+		os.Exit(1)
+	}
+	httpServer := &http.Server{
+		Addr:         *addr,
+		Handler:      srv,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+	log.Printf("serving partial decryptions on %s", *addr)
+	if *certPath != "" {
+		err = httpServer.ListenAndServeTLS(*certPath, *keyPath)
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	log.Fatal(err)
+}
+
+// newServer returns A node server with the key share and client policies on the paths provided.
+func newServer(sharePath, sealedPath, clientsPath string) (*node.Server, error) {
+	if (sharePath == "") == (sealedPath == "") || clientsPath == "" {
+		return nil, fmt.Errorf("clients file and either a share or a sealed share file are required")
+	}
+	var signer tcpaillier.ShareSigner
+	if sealedPath != "" {
+		passphrase := os.Getenv("TCPAILLIER_PASSPHRASE")
+		if passphrase == "" {
+			return nil, fmt.Errorf("TCPAILLIER_PASSPHRASE is not defined")
+		}
+		fileSigner, err := keystore.NewFileSigner(sealedPath, []byte(passphrase), nil)
+		if err != nil {
+			return nil, err
+		}
+		signer = fileSigner
+	} else {
+		var share tcpaillier.KeyShare
+		if err := readJSON(sharePath, &share); err != nil {
+			return nil, err
+		}
+		signer = &share
+	}
+	var policies []*node.ClientPolicy
+	if err := readJSON(clientsPath, &policies); err != nil {
+		return nil, err
+	}
+	return node.NewServer(signer, policies...)
+}
+
+func readJSON(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("cannot decode %s: %v", path, err)
+	}
+	return nil
+}
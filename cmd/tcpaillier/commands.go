@@ -0,0 +1,277 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+// newFlagSet returns the flag set of A subcommand, that writes its errors to stderr.
+func newFlagSet(name, usage string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: tcpaillier %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// keygen generates A key and writes the public key and the key shares on A directory.
+func keygen(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("keygen", "[-bits 2048] [-s 1] -l 5 -k 3 [-out .]", stderr)
+	bitSize := fs.Int("bits", 2048, "bit size of the modulus")
+	s := fs.Uint("s", 1, "exponent of the modulus in the encryption space")
+	l := fs.Uint("l", 0, "number of key shares")
+	k := fs.Uint("k", 0, "number of key shares needed to decrypt")
+	out := fs.String("out", ".", "directory where the key files are written")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *s > 255 || *l > 255 || *k > 255 {
+		return fmt.Errorf("s, l and k should be at most 255")
+	}
+	shares, pk, err := tcpaillier.NewKey(*bitSize, uint8(*s), uint8(*l), uint8(*k))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*out, 0700); err != nil {
+		return err
+	}
+	pkPath := filepath.Join(*out, "pubkey.json")
+	if err := writeJSONFile(pkPath, pk, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, pkPath)
+	for _, share := range shares {
+		sharePath := filepath.Join(*out, fmt.Sprintf("share-%d.json", share.Index))
+		if err := writeJSONFile(sharePath, share, 0600); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, sharePath)
+	}
+	return nil
+}
+
+// encrypt encrypts A message with A proof.
+func encrypt(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("encrypt", "-pub pubkey.json message", stderr)
+	pkPath := fs.String("pub", "", "public key file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("encrypt needs one message")
+	}
+	pk, err := readPubKey(*pkPath)
+	if err != nil {
+		return err
+	}
+	msg, err := parseInt(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	c, proof, err := pk.EncryptWithProof(msg)
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, &ciphertext{
+		Ciphertext:   *pk.NewCiphertext(c),
+		EncryptProof: proof,
+	})
+}
+
+// add adds encrypted values.
+func add(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("add", "-pub pubkey.json c1.json c2.json ...", stderr)
+	pkPath := fs.String("pub", "", "public key file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("add needs at least one encrypted value")
+	}
+	pk, err := readPubKey(*pkPath)
+	if err != nil {
+		return err
+	}
+	cs := make([]*big.Int, fs.NArg())
+	for i, path := range fs.Args() {
+		c, err := readCiphertext(path, pk)
+		if err != nil {
+			return err
+		}
+		cs[i] = c.C
+	}
+	sum, err := pk.Add(cs...)
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, &ciphertext{Ciphertext: *pk.NewCiphertext(sum)})
+}
+
+// mul multiplies an encrypted value by A constant with A proof.
+func mul(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("mul", "-pub pubkey.json -c c.json constant", stderr)
+	pkPath := fs.String("pub", "", "public key file")
+	cPath := fs.String("c", "", "encrypted value file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *cPath == "" {
+		return fmt.Errorf("mul needs one encrypted value and one constant")
+	}
+	pk, err := readPubKey(*pkPath)
+	if err != nil {
+		return err
+	}
+	c, err := readCiphertext(*cPath, pk)
+	if err != nil {
+		return err
+	}
+	constant, err := parseInt(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	result, proof, err := pk.MultiplyWithProof(c.C, constant)
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, &ciphertext{
+		Ciphertext: *pk.NewCiphertext(result),
+		MulProof:   proof,
+	})
+}
+
+// partialDecrypt decrypts partially an encrypted value with A proof.
+func partialDecrypt(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("partial-decrypt", "-share share.json c.json", stderr)
+	sharePath := fs.String("share", "", "key share file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("partial-decrypt needs one encrypted value")
+	}
+	share, err := readKeyShare(*sharePath)
+	if err != nil {
+		return err
+	}
+	c, err := readCiphertext(fs.Arg(0), share.PubKey)
+	if err != nil {
+		return err
+	}
+	ds, proof, err := share.PartialDecryptWithProof(c.C)
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, &decryptionShare{
+		Share: ds,
+		Proof: proof,
+	})
+}
+
+// combine combines decryption shares and writes the decrypted value. If the encrypted
+// value is provided, the proofs of the shares are verified first.
+func combine(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("combine", "-pub pubkey.json [-c c.json] ds1.json ds2.json ...", stderr)
+	pkPath := fs.String("pub", "", "public key file")
+	cPath := fs.String("c", "", "encrypted value file, used to verify the shares")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	pk, err := readPubKey(*pkPath)
+	if err != nil {
+		return err
+	}
+	var c *ciphertext
+	if *cPath != "" {
+		if c, err = readCiphertext(*cPath, pk); err != nil {
+			return err
+		}
+	}
+	shares := make([]*tcpaillier.DecryptionShare, fs.NArg())
+	for i, path := range fs.Args() {
+		ds, err := readDecryptionShare(path)
+		if err != nil {
+			return err
+		}
+		if c != nil {
+			if ds.Proof == nil {
+				return fmt.Errorf("decryption share on %s has not a proof", path)
+			}
+			if err := ds.Proof.Verify(pk, c.C, ds.Share); err != nil {
+				return fmt.Errorf("decryption share on %s is invalid: %v", path, err)
+			}
+		}
+		shares[i] = ds.Share
+	}
+	dec, err := pk.CombineShares(shares...)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, dec)
+	return err
+}
+
+// verify verifies the proof of an encrypted value or A decryption share. The proofs of
+// multiplications and decryption shares need the encrypted value they were computed from.
+func verify(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("verify", "-pub pubkey.json [-c c.json] file.json", stderr)
+	pkPath := fs.String("pub", "", "public key file")
+	cPath := fs.String("c", "", "encrypted value file the proof was computed from")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("verify needs one file")
+	}
+	pk, err := readPubKey(*pkPath)
+	if err != nil {
+		return err
+	}
+	var from *ciphertext
+	if *cPath != "" {
+		if from, err = readCiphertext(*cPath, pk); err != nil {
+			return err
+		}
+	}
+	path := fs.Arg(0)
+	if ds, err := readDecryptionShare(path); err == nil {
+		if from == nil || ds.Proof == nil {
+			return fmt.Errorf("decryption share proofs need the proof and the encrypted value")
+		}
+		err = ds.Proof.Verify(pk, from.C, ds.Share)
+		return report(stdout, path, err)
+	}
+	c, err := readCiphertext(path, pk)
+	if err != nil {
+		return err
+	}
+	switch {
+	case c.EncryptProof != nil:
+		err = c.EncryptProof.Verify(pk, c.C)
+	case c.MulProof != nil:
+		if from == nil {
+			return fmt.Errorf("multiplication proofs need the encrypted value")
+		}
+		err = c.MulProof.Verify(pk, c.C, from.C)
+	default:
+		return fmt.Errorf("%s has not a proof", path)
+	}
+	return report(stdout, path, err)
+}
+
+// report writes that the proof on path is valid, or returns the error of its verification.
+func report(stdout io.Writer, path string, err error) error {
+	if err != nil {
+		return fmt.Errorf("proof on %s is invalid: %v", path, err)
+	}
+	_, err = fmt.Fprintf(stdout, "%s: valid\n", path)
+	return err
+}
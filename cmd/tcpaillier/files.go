@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/node"
+)
+
+// ciphertext is the format of an encrypted value, tagged with the fingerprint of its
+// public key. It has A proof of its encryption if it was encrypted by the tool, or A
+// proof of its multiplication by A constant if it was multiplied by the tool.
+type ciphertext struct {
+	tcpaillier.Ciphertext
+	EncryptProof *tcpaillier.EncryptZK `json:",omitempty"`
+	MulProof     *tcpaillier.MulZK     `json:",omitempty"`
+}
+
+// decryptionShare is the format of A decryption share and its proof. It is the same
+// one of the answers of A node.
+type decryptionShare = node.DecryptResponse
+
+func readJSON(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("cannot decode %s: %v", path, err)
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeJSONFile(path string, v interface{}, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(f, v); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readPubKey(path string) (*tcpaillier.PubKey, error) {
+	if path == "" {
+		return nil, fmt.Errorf("public key file is required")
+	}
+	// The public key is validated when it is decoded.
+	var pk tcpaillier.PubKey
+	if err := readJSON(path, &pk); err != nil {
+		return nil, err
+	}
+	return &pk, nil
+}
+
+func readKeyShare(path string) (*tcpaillier.KeyShare, error) {
+	if path == "" {
+		return nil, fmt.Errorf("key share file is required")
+	}
+	var share tcpaillier.KeyShare
+	if err := readJSON(path, &share); err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// readCiphertext reads an encrypted value and checks that it belongs to the public key.
+func readCiphertext(path string, pk *tcpaillier.PubKey) (*ciphertext, error) {
+	var c ciphertext
+	if err := readJSON(path, &c); err != nil {
+		return nil, err
+	}
+	if err := c.Check(pk); err != nil {
+		return nil, fmt.Errorf("encrypted value on %s is invalid: %w", path, err)
+	}
+	return &c, nil
+}
+
+func readDecryptionShare(path string) (*decryptionShare, error) {
+	var ds decryptionShare
+	if err := readJSON(path, &ds); err != nil {
+		return nil, err
+	}
+	if ds.Share == nil || ds.Share.Ci == nil {
+		return nil, fmt.Errorf("decryption share on %s is not defined", path)
+	}
+	return &ds, nil
+}
+
+// parseInt parses A non negative decimal integer.
+func parseInt(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 {
+		return nil, fmt.Errorf("%q is not a non negative decimal integer", s)
+	}
+	return n, nil
+}
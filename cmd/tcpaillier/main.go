@@ -0,0 +1,65 @@
+// Command tcpaillier generates threshold Paillier keys, and encrypts, operates and
+// decrypts values with them.
+//
+// Usage:
+//
+//	tcpaillier keygen [-bits 2048] [-s 1] -l 5 -k 3 [-out .]
+//	tcpaillier encrypt -pub pubkey.json message
+//	tcpaillier add -pub pubkey.json c1.json c2.json ...
+//	tcpaillier mul -pub pubkey.json -c c.json constant
+//	tcpaillier partial-decrypt -share share-1.json c.json
+//	tcpaillier combine -pub pubkey.json [-c c.json] ds1.json ds2.json ...
+//	tcpaillier verify -pub pubkey.json [-c c.json] file.json
+//
+// Keys, key shares, encrypted values and decryption shares are read and written as JSON.
+// Encrypted values and decryption shares are written to the standard output.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command is A subcommand of the tool, that receives its arguments.
+type command func(args []string, stdout, stderr io.Writer) error
+
+var commands = map[string]command{
+	"keygen":          keygen,
+	"encrypt":         encrypt,
+	"add":             add,
+	"mul":             mul,
+	"partial-decrypt": partialDecrypt,
+	"combine":         combine,
+	"verify":          verify,
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "tcpaillier: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run executes the subcommand on the first argument with the rest of them.
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command, it should be one of %s", commandNames())
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q, it should be one of %s", args[0], commandNames())
+	}
+	return cmd(args[1:], stdout, stderr)
+}
+
+func commandNames() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runTo runs the tool with the arguments provided and returns its output. If out is not
+// empty, the output is also written on that file of dir.
+func runTo(t *testing.T, dir, out string, args ...string) string {
+	var stdout, stderr bytes.Buffer
+	if err := run(args, &stdout, &stderr); err != nil {
+		t.Fatalf("%s failed: %v\n%s", args[0], err, stderr.String())
+	}
+	if out != "" {
+		if err := ioutil.WriteFile(filepath.Join(dir, out), stdout.Bytes(), 0600); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	return stdout.String()
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tcpaillier")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	runTo(t, dir, "", "keygen", "-bits", "256", "-l", "3", "-k", "2", "-out", dir)
+	pub := path("pubkey.json")
+	runTo(t, dir, "a.json", "encrypt", "-pub", pub, "20")
+	runTo(t, dir, "b.json", "encrypt", "-pub", pub, "22")
+	runTo(t, dir, "sum.json", "add", "-pub", pub, path("a.json"), path("b.json"))
+	runTo(t, dir, "mul.json", "mul", "-pub", pub, "-c", path("sum.json"), "3")
+	runTo(t, dir, "ds1.json", "partial-decrypt", "-share", path("share-1.json"), path("mul.json"))
+	runTo(t, dir, "ds3.json", "partial-decrypt", "-share", path("share-3.json"), path("mul.json"))
+
+	runTo(t, dir, "", "verify", "-pub", pub, path("a.json"))
+	runTo(t, dir, "", "verify", "-pub", pub, "-c", path("sum.json"), path("mul.json"))
+	runTo(t, dir, "", "verify", "-pub", pub, "-c", path("mul.json"), path("ds1.json"))
+
+	dec := runTo(t, dir, "", "combine", "-pub", pub, "-c", path("mul.json"), path("ds1.json"), path("ds3.json"))
+	if strings.TrimSpace(dec) != "126" {
+		t.Errorf("decrypted value should be 126, but it is %s", dec)
+	}
+
+	// A decryption share should not be valid for another encrypted value.
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"verify", "-pub", pub, "-c", path("a.json"), path("ds1.json")}, &stdout, &stderr); err == nil {
+		t.Errorf("decryption share should not be valid for another encrypted value")
+	}
+	if err := run([]string{"unknown"}, &stdout, &stderr); err == nil {
+		t.Errorf("unknown command should fail")
+	}
+}
@@ -0,0 +1,96 @@
+package tcpaillier
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Combiner joins the decryption shares of A fixed set of share indexes. It precomputes
+// the Lagrange exponents 2*Delta*lambda_i of every share once, and it combines the shares
+// using simultaneous exponentiation. When the same set of key shares decrypts many values,
+// it is faster than calling CombineShares on each one of them.
+type Combiner struct {
+	pk        *PubKey
+	fp        Fingerprint
+	indexes   []uint8
+	exps      []*big.Int
+	positions map[uint8]int
+}
+
+// NewCombiner returns A combiner for the shares with the indexes provided. There must be
+// exactly K different indexes, between 1 and L.
+func (pk *PubKey) NewCombiner(indexes ...uint8) (co *Combiner, err error) {
+	if len(indexes) < int(pk.K) {
+		err = &InsufficientSharesError{Have: len(indexes), Need: int(pk.K)}
+		return
+	}
+	if len(indexes) > int(pk.K) {
+		err = fmt.Errorf("needed exactly %d indexes, but got %d", pk.K, len(indexes))
+		return
+	}
+	positions := make(map[uint8]int)
+	for i, index := range indexes {
+		if index < 1 || index > pk.L {
+			err = fmt.Errorf("index %d should be between 1 and %d", index, pk.L)
+			return
+		}
+		if j, ok := positions[index]; ok {
+			err = fmt.Errorf("index %d repeated on positions %d and %d", index, i, j)
+			return
+		}
+		positions[index] = i
+	}
+	pk.Cache()
+	co = &Combiner{
+		pk:        pk,
+		fp:        pk.Fingerprint(),
+		indexes:   append([]uint8{}, indexes...),
+		exps:      pk.lagrangeExponents(indexes),
+		positions: positions,
+	}
+	return
+}
+
+// Indexes returns the share indexes the combiner was created for.
+func (co *Combiner) Indexes() []uint8 {
+	return append([]uint8{}, co.indexes...)
+}
+
+// Combine joins partial decryptions of A value and returns the decrypted value. There
+// must be exactly one share for each index of the combiner, in any order.
+func (co *Combiner) Combine(shares ...*DecryptionShare) (dec *big.Int, err error) {
+	if len(shares) < len(co.indexes) {
+		err = &InsufficientSharesError{Have: len(shares), Need: len(co.indexes)}
+		return
+	}
+	if len(shares) > len(co.indexes) {
+		err = fmt.Errorf("needed exactly %d shares to decrypt, but got %d", len(co.indexes), len(shares))
+		return
+	}
+	cis := make([]*big.Int, len(shares))
+	for _, share := range shares {
+		i, ok := co.positions[share.Index]
+		if !ok {
+			err = fmt.Errorf("share %d does not belong to the combiner", share.Index)
+			return
+		}
+		if cis[i] != nil {
+			err = fmt.Errorf("%w: share %d", ErrRepeatedShare, share.Index)
+			return
+		}
+		if err = checkFingerprint(fmt.Sprintf("share %d", share.Index), share.Fingerprint, co.fp); err != nil {
+			return
+		}
+		if err = co.pk.validateCiphertext(fmt.Sprintf("share %d", share.Index), share.Ci); err != nil {
+			return
+		}
+		cis[i] = share.Ci
+	}
+	cPrime := multiExp(cis, co.exps, co.pk.Cache().NToSPlusOne)
+	if cPrime == nil {
+		err = fmt.Errorf("shares are not invertible modulo N^(s+1)")
+		return
+	}
+	dec = co.pk.decode(cPrime)
+	return
+}
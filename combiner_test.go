@@ -0,0 +1,108 @@
+package tcpaillier_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+func TestCombiner_Combine(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	committee := shares[l-k:]
+	indexes := make([]uint8, len(committee))
+	for i, share := range committee {
+		indexes[i] = share.Index
+	}
+	combiner, err := pk.NewCombiner(indexes...)
+	if err != nil {
+		t.Errorf("cannot create combiner: %v", err)
+		return
+	}
+	for _, msg := range []*big.Int{twelve, twentyFive, threeHundred} {
+		encrypted, _, err := pk.Encrypt(msg)
+		if err != nil {
+			t.Errorf("%v", err)
+			return
+		}
+		decryptShares := make([]*tcpaillier.DecryptionShare, len(committee))
+		for i, share := range committee {
+			ds, err := share.PartialDecrypt(encrypted)
+			if err != nil {
+				t.Errorf("share %d is not able to decrypt partially the message: %v", share.Index, err)
+				return
+			}
+			// The combiner should accept the shares in any order.
+			decryptShares[len(committee)-i-1] = ds
+		}
+		decrypted, err := combiner.Combine(decryptShares...)
+		if err != nil {
+			t.Errorf("cannot combine shares: %v", err)
+			return
+		}
+		if decrypted.Cmp(msg) != 0 {
+			t.Errorf("messages are different. Decrypted is %s and original was %s.", decrypted, msg)
+			return
+		}
+		decryptShares[0] = decryptShares[1]
+		if _, err := combiner.Combine(decryptShares...); !errors.Is(err, tcpaillier.ErrRepeatedShare) {
+			t.Errorf("combiner should reject repeated shares with %v, but it fails with %v", tcpaillier.ErrRepeatedShare, err)
+			return
+		}
+	}
+	if _, err := pk.NewCombiner(indexes[1:]...); err == nil {
+		t.Errorf("combiner should not be created with less than K indexes")
+	}
+}
+
+func benchmarkShares(b *testing.B) (*tcpaillier.PubKey, []*tcpaillier.DecryptionShare) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		b.Fatalf("%v", err)
+	}
+	encrypted, _, err := pk.Encrypt(twelve)
+	if err != nil {
+		b.Fatalf("%v", err)
+	}
+	decryptShares := make([]*tcpaillier.DecryptionShare, k)
+	for i, share := range shares[:k] {
+		decryptShares[i], err = share.PartialDecrypt(encrypted)
+		if err != nil {
+			b.Fatalf("%v", err)
+		}
+	}
+	return pk, decryptShares
+}
+
+func BenchmarkPubKey_CombineShares(b *testing.B) {
+	pk, decryptShares := benchmarkShares(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pk.CombineShares(decryptShares...); err != nil {
+			b.Fatalf("%v", err)
+		}
+	}
+}
+
+func BenchmarkCombiner_Combine(b *testing.B) {
+	pk, decryptShares := benchmarkShares(b)
+	indexes := make([]uint8, len(decryptShares))
+	for i, ds := range decryptShares {
+		indexes[i] = ds.Index
+	}
+	combiner, err := pk.NewCombiner(indexes...)
+	if err != nil {
+		b.Fatalf("%v", err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := combiner.Combine(decryptShares...); err != nil {
+			b.Fatalf("%v", err)
+		}
+	}
+}
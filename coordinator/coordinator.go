@@ -0,0 +1,226 @@
+// Package coordinator gathers decryption shares from remote key share holders and
+// combines them. It requests A share from every holder at the same time, verifies each
+// one of them as it arrives, and combines the first K valid ones.
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+// Transport represents the way A coordinator talks with the key share holders.
+type Transport interface {
+	// RequestShare asks the holder on endpoint for its partial decryption of c and the proof
+	// of it. It must return when ctx is done.
+	RequestShare(ctx context.Context, endpoint string, c *big.Int) (*tcpaillier.DecryptionShare, *tcpaillier.DecryptShareZK, error)
+}
+
+// Status represents what happened with the request to A holder.
+type Status int
+
+const (
+	// StatusSlow means that the holder did not answer before the decryption finished.
+	StatusSlow Status = iota
+	// StatusValid means that the holder sent A valid share, used to decrypt the value.
+	StatusValid
+	// StatusLate means that the holder sent A valid share after the value was decrypted.
+	StatusLate
+	// StatusFaulty means that the holder returned an error, an invalid share or A share
+	// with an index already used by another holder.
+	StatusFaulty
+)
+
+func (status Status) String() string {
+	switch status {
+	case StatusSlow:
+		return "slow"
+	case StatusValid:
+		return "valid"
+	case StatusLate:
+		return "late"
+	case StatusFaulty:
+		return "faulty"
+	default:
+		return fmt.Sprintf("Status(%d)", int(status))
+	}
+}
+
+// HolderReport represents the outcome of the request to one holder. Index is the index of
+// the share it sent, and Latency is the time it took to answer. Err is defined only if the
+// holder is faulty.
+type HolderReport struct {
+	Endpoint string
+	Status   Status
+	Index    uint8
+	Latency  time.Duration
+	Err      error
+}
+
+// Report contains A HolderReport for each endpoint of A coordinator, in the same order.
+type Report struct {
+	Holders []*HolderReport
+}
+
+// Faulty returns the endpoints of the holders that were faulty.
+func (report *Report) Faulty() []string {
+	return report.endpoints(StatusFaulty)
+}
+
+// Slow returns the endpoints of the holders that did not answer in time.
+func (report *Report) Slow() []string {
+	return report.endpoints(StatusSlow)
+}
+
+func (report *Report) endpoints(status Status) []string {
+	var endpoints []string
+	for _, holder := range report.Holders {
+		if holder.Status == status {
+			endpoints = append(endpoints, holder.Endpoint)
+		}
+	}
+	return endpoints
+}
+
+// Coordinator requests decryption shares to A set of holders using A transport.
+// After A value is decrypted, it waits at most LateWait for the remaining holders
+// to classify them as late or faulty. If LateWait is zero, the requests still
+// pending when the value is decrypted are cancelled.
+type Coordinator struct {
+	PubKey    *tcpaillier.PubKey
+	Transport Transport
+	Endpoints []string
+	LateWait  time.Duration
+}
+
+// response is the answer of A holder.
+type response struct {
+	holder  int
+	share   *tcpaillier.DecryptionShare
+	proof   *tcpaillier.DecryptShareZK
+	err     error
+	latency time.Duration
+}
+
+// New returns A coordinator for the holders on the endpoints provided.
+func New(pk *tcpaillier.PubKey, transport Transport, endpoints ...string) *Coordinator {
+	return &Coordinator{
+		PubKey:    pk,
+		Transport: transport,
+		Endpoints: endpoints,
+	}
+}
+
+// Decrypt requests A decryption share of c to every holder, and returns the decrypted value
+// as soon as K valid shares are received. If ctx is done before that, it returns an error
+// wrapping the context error, and if there are not enough valid shares, it returns an error
+// wrapping A tcpaillier.InsufficientSharesError. The report is returned in all cases.
+func (co *Coordinator) Decrypt(ctx context.Context, c *big.Int) (dec *big.Int, report *Report, err error) {
+	pk := co.PubKey
+	pk.Cache()
+	report = &Report{
+		Holders: make([]*HolderReport, len(co.Endpoints)),
+	}
+	for i, endpoint := range co.Endpoints {
+		report.Holders[i] = &HolderReport{
+			Endpoint: endpoint,
+			Status:   StatusSlow,
+		}
+	}
+	if len(co.Endpoints) < int(pk.K) {
+		err = fmt.Errorf("needed %d holders to decrypt, but there are %d", pk.K, len(co.Endpoints))
+		return
+	}
+
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	responses := make(chan *response, len(co.Endpoints))
+	start := time.Now()
+	for i, endpoint := range co.Endpoints {
+		go func(i int, endpoint string) {
+			share, proof, err := co.Transport.RequestShare(reqCtx, endpoint, c)
+			responses <- &response{
+				holder:  i,
+				share:   share,
+				proof:   proof,
+				err:     err,
+				latency: time.Since(start),
+			}
+		}(i, endpoint)
+	}
+
+	used := make(map[uint8]int)
+	var shares []*tcpaillier.DecryptionShare
+	pending := len(co.Endpoints)
+	for dec == nil && pending > 0 {
+		select {
+		case resp := <-responses:
+			pending--
+			if co.classify(report, resp, c, used, StatusValid) {
+				shares = append(shares, resp.share)
+				if len(shares) == int(pk.K) {
+					dec, err = pk.CombineShares(shares...)
+					if err != nil {
+						return
+					}
+				}
+			}
+		case <-ctx.Done():
+			err = fmt.Errorf("got %d of %d valid shares before the deadline: %w", len(shares), pk.K, ctx.Err())
+			return
+		}
+	}
+	if dec == nil {
+		err = fmt.Errorf("%d holders are faulty: %w", len(report.Faulty()), &tcpaillier.InsufficientSharesError{
+			Have: len(shares),
+			Need: int(pk.K),
+		})
+		return
+	}
+
+	if co.LateWait <= 0 || pending == 0 {
+		return
+	}
+	timer := time.NewTimer(co.LateWait)
+	defer timer.Stop()
+	for ; pending > 0; pending-- {
+		select {
+		case resp := <-responses:
+			co.classify(report, resp, c, used, StatusLate)
+		case <-timer.C:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+	return
+}
+
+// classify updates the report of the holder of A response, giving it the status provided
+// if its share is valid. It returns true if the share is valid.
+func (co *Coordinator) classify(report *Report, resp *response, c *big.Int, used map[uint8]int, status Status) bool {
+	holder := report.Holders[resp.holder]
+	holder.Latency = resp.latency
+	holder.Status = StatusFaulty
+	switch {
+	case resp.err != nil:
+		holder.Err = resp.err
+	case resp.share == nil || resp.proof == nil:
+		holder.Err = fmt.Errorf("holder did not send its share and proof")
+	default:
+		holder.Index = resp.share.Index
+		if j, ok := used[resp.share.Index]; ok {
+			holder.Err = fmt.Errorf("share %d was already sent by %s", resp.share.Index, co.Endpoints[j])
+		} else if err := resp.proof.Verify(co.PubKey, c, resp.share); err != nil {
+			holder.Err = err
+		} else {
+			used[resp.share.Index] = resp.holder
+			holder.Status = status
+			return true
+		}
+	}
+	return false
+}
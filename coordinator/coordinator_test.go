@@ -0,0 +1,160 @@
+package coordinator_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/coordinator"
+)
+
+const k = 3
+const l = 5
+const s = 1
+
+const bitSize = 256
+
+// holder is the behaviour of A fake key share holder.
+type holder struct {
+	share *tcpaillier.KeyShare
+	delay time.Duration
+	fail  bool
+	wrong bool
+}
+
+// memTransport is A transport to fake holders on the same process.
+type memTransport map[string]*holder
+
+func (transport memTransport) RequestShare(ctx context.Context, endpoint string, c *big.Int) (*tcpaillier.DecryptionShare, *tcpaillier.DecryptShareZK, error) {
+	h, ok := transport[endpoint]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown endpoint %s", endpoint)
+	}
+	select {
+	case <-time.After(h.delay):
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	if h.fail {
+		return nil, nil, fmt.Errorf("holder is down")
+	}
+	ds, zk, err := h.share.PartialDecryptWithProof(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if h.wrong {
+		ds.Ci = new(big.Int).Add(ds.Ci, big.NewInt(1))
+	}
+	return ds, zk, nil
+}
+
+func newTransport(t *testing.T, behaviours ...holder) (memTransport, []string, *tcpaillier.PubKey) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	transport := make(memTransport)
+	endpoints := make([]string, len(behaviours))
+	for i := range behaviours {
+		h := behaviours[i]
+		h.share = shares[i]
+		endpoints[i] = fmt.Sprintf("holder-%d", i+1)
+		transport[endpoints[i]] = &h
+	}
+	return transport, endpoints, pk
+}
+
+func TestCoordinator_Decrypt(t *testing.T) {
+	transport, endpoints, pk := newTransport(t,
+		holder{fail: true},
+		holder{wrong: true},
+		holder{},
+		holder{},
+		holder{delay: 50 * time.Millisecond},
+	)
+	c, _, err := pk.Encrypt(big.NewInt(42))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	co := coordinator.New(pk, transport, endpoints...)
+	dec, report, err := co.Decrypt(context.Background(), c)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if dec.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("decrypted value should be 42, but it is %s", dec)
+	}
+	expected := []coordinator.Status{
+		coordinator.StatusFaulty,
+		coordinator.StatusFaulty,
+		coordinator.StatusValid,
+		coordinator.StatusValid,
+		coordinator.StatusValid,
+	}
+	for i, h := range report.Holders {
+		if h.Status != expected[i] {
+			t.Errorf("holder %s should be %s, but it is %s", h.Endpoint, expected[i], h.Status)
+		}
+	}
+	if faulty := report.Faulty(); len(faulty) != 2 {
+		t.Errorf("there should be 2 faulty holders, but there are %v", faulty)
+	}
+}
+
+func TestCoordinator_Decrypt_late(t *testing.T) {
+	transport, endpoints, pk := newTransport(t,
+		holder{},
+		holder{},
+		holder{},
+		holder{delay: 100 * time.Millisecond},
+		holder{delay: time.Minute},
+	)
+	c, _, err := pk.Encrypt(big.NewInt(7))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	co := coordinator.New(pk, transport, endpoints...)
+	co.LateWait = time.Second
+	start := time.Now()
+	dec, report, err := co.Decrypt(context.Background(), c)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if dec.Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("decrypted value should be 7, but it is %s", dec)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("coordinator waited %v for the slow holder", elapsed)
+	}
+	if status := report.Holders[3].Status; status != coordinator.StatusLate {
+		t.Errorf("holder 4 should be late, but it is %s", status)
+	}
+	if slow := report.Slow(); len(slow) != 1 || slow[0] != endpoints[4] {
+		t.Errorf("slow holders should be [%s], but they are %v", endpoints[4], slow)
+	}
+}
+
+func TestCoordinator_Decrypt_deadline(t *testing.T) {
+	transport, endpoints, pk := newTransport(t,
+		holder{},
+		holder{},
+		holder{delay: time.Minute},
+		holder{delay: time.Minute},
+		holder{fail: true},
+	)
+	c, _, err := pk.Encrypt(big.NewInt(7))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	co := coordinator.New(pk, transport, endpoints...)
+	if _, report, err := co.Decrypt(ctx, c); err == nil {
+		t.Errorf("decryption should fail when the deadline is reached")
+	} else if slow := report.Slow(); len(slow) != 2 {
+		t.Errorf("there should be 2 slow holders, but there are %v", slow)
+	}
+}
@@ -6,8 +6,11 @@ import (
 
 // DecryptionShare represents A partial decryption of A value
 // and the ZKProof of that decryption. It complies with ZKProof
-// interface.
+// interface. Fingerprint is the fingerprint of the public key of
+// the key share, and it is checked when the share is used if it
+// is defined.
 type DecryptionShare struct {
+	Fingerprint Fingerprint `json:",omitempty"`
 	Index       uint8
 	Ci          *big.Int
 }
@@ -0,0 +1,65 @@
+package tcpaillier
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	// ErrNilCiphertext is returned when an encrypted value is not defined.
+	ErrNilCiphertext = errors.New("encrypted value is not defined")
+	// ErrCiphertextOutOfRange is returned when an encrypted value is not between 1 (inclusive)
+	// and N^(s+1) (exclusive).
+	ErrCiphertextOutOfRange = errors.New("encrypted value must be between 1 (inclusive) and N^(s+1) (exclusive)")
+	// ErrCiphertextNotCoprime is returned when an encrypted value is not coprime with N, so it
+	// is not A valid encryption and it would reveal A factor of N.
+	ErrCiphertextNotCoprime = errors.New("encrypted value must be coprime with N")
+	// ErrInvalidProof is returned when A ZKProof is not valid for the values provided.
+	ErrInvalidProof = errors.New("zkproof failed")
+	// ErrInvalidParams is returned when the parameters of A new key are not valid.
+	ErrInvalidParams = errors.New("invalid key parameters")
+	// ErrInvalidPubKey is returned when the values of A public key are not consistent.
+	ErrInvalidPubKey = errors.New("invalid public key")
+	// ErrKeyShareDestroyed is returned when A destroyed key share is used.
+	ErrKeyShareDestroyed = errors.New("key share was destroyed")
+	// ErrFingerprintMismatch is returned when A key share, an encrypted value or A decryption
+	// share has the fingerprint of another public key.
+	ErrFingerprintMismatch = errors.New("public key fingerprint does not match")
+	// ErrRepeatedShare is returned when A list of decryption shares has two shares with the
+	// same index.
+	ErrRepeatedShare = errors.New("decryption share repeated")
+)
+
+// InsufficientSharesError is returned when there are less decryption shares than the ones
+// needed to decrypt A value.
+type InsufficientSharesError struct {
+	Have, Need int
+}
+
+func (e *InsufficientSharesError) Error() string {
+	return fmt.Sprintf("needed %d shares to decrypt, but got %d", e.Need, e.Have)
+}
+
+// ValidateCiphertext checks that c is A valid encrypted value for the public key. The errors
+// returned wrap ErrNilCiphertext, ErrCiphertextOutOfRange or ErrCiphertextNotCoprime.
+func (pk *PubKey) ValidateCiphertext(c *big.Int) error {
+	if c == nil {
+		return ErrNilCiphertext
+	}
+	if c.Sign() <= 0 || c.Cmp(pk.Cache().NToSPlusOne) >= 0 {
+		return ErrCiphertextOutOfRange
+	}
+	if new(big.Int).GCD(nil, nil, c, pk.N).Cmp(one) != 0 {
+		return ErrCiphertextNotCoprime
+	}
+	return nil
+}
+
+// validateCiphertext validates an encrypted value, and adds its name to the error.
+func (pk *PubKey) validateCiphertext(name string, c *big.Int) error {
+	if err := pk.ValidateCiphertext(c); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,395 @@
+// Package escrow escrows secrets, like database master keys, to the committee of A threshold
+// Paillier key. A depositor encrypts A secret with A proof of encryption, labels it with A
+// policy and signs the resulting record. A recovery of the secret is A request that the key
+// share holders approve with their identity keys. The secret is recovered only after K
+// holders approved it, and the signed approvals are the audit trail of the recovery. The
+// secret is encrypted as A labeled ciphertext bound to the identifier, the policy and the
+// depositor of its record, so it cannot be copied to another record with A different policy.
+//
+// Partial decryptions are deterministic, so A decryption share of the record can be used
+// again to recover the same secret. Holders seal their shares to the recipient key of the
+// requester (see the recipient package), and the audit trail keeps only the hashes of the
+// shares, so it can be published without revealing the secret.
+package escrow
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/recipient"
+)
+
+// Version is the version of the format of the records.
+const Version = 1
+
+// idSize is the size in bytes of the random identifiers of records and recoveries.
+const idSize = 16
+
+// Domains separate the signatures of records from the ones of approvals, and the labels
+// of the encrypted secrets.
+const (
+	recordDomain   = "tcpaillier escrow record v1"
+	approvalDomain = "tcpaillier escrow approval v1"
+	labelDomain    = "tcpaillier escrow label v1"
+)
+
+// Record is A secret escrowed to A committee. Ciphertext is the encryption of the secret,
+// with A proof of encryption bound to A label that is the hash of ID, Policy and Depositor.
+// Policy states the conditions under which the secret can be recovered, and it is signed
+// with the rest of the record by the depositor.
+type Record struct {
+	Version     int
+	ID          string
+	Fingerprint tcpaillier.Fingerprint
+	Policy      string
+	Depositor   ed25519.PublicKey
+	Created     time.Time
+	Ciphertext  *tcpaillier.LabeledCiphertext
+	Signature   []byte
+}
+
+// Recovery is A request to recover the secret of A record, and the approvals of the key
+// share holders. If Holders is not nil, it has the identity key of the holder of each
+// share index, and only those holders can approve the recovery. RequesterKey is the
+// recipient key the decryption shares are sealed to.
+type Recovery struct {
+	ID           string
+	RecordID     string
+	Requester    string
+	RequesterKey recipient.PublicKey
+	Reason       string
+	Created      time.Time
+	Holders      map[uint8]ed25519.PublicKey `json:",omitempty"`
+	Approvals    []*Approval
+}
+
+// Approval is the approval of A recovery by A key share holder, signed with its identity
+// key. ShareHash is the hash of the decryption share of the secret of the holder, and Sealed
+// is that share sealed to the requester. Sealed is not kept in the audit trail.
+type Approval struct {
+	RecoveryID string
+	Index      uint8
+	Approver   ed25519.PublicKey
+	Created    time.Time
+	ShareHash  []byte
+	Signature  []byte
+	Sealed     *recipient.SealedShare `json:",omitempty"`
+}
+
+// Deposit escrows the secret to the committee of the public key, with the policy provided,
+// and signs the record with the key of the depositor.
+func Deposit(pk *tcpaillier.PubKey, secret []byte, policy string, depositor ed25519.PrivateKey) (record *Record, err error) {
+	m, err := encode(pk, secret)
+	if err != nil {
+		return
+	}
+	id, err := newID()
+	if err != nil {
+		return
+	}
+	rec := &Record{
+		Version:     Version,
+		ID:          id,
+		Fingerprint: pk.Fingerprint(),
+		Policy:      policy,
+		Depositor:   depositor.Public().(ed25519.PublicKey),
+		Created:     time.Now().UTC(),
+	}
+	if rec.Ciphertext, err = pk.EncryptLabeled(m, rec.label()); err != nil {
+		return
+	}
+	record = rec
+	record.Signature = ed25519.Sign(depositor, record.digest())
+	return
+}
+
+// Verify checks that the record belongs to the public key, that it is signed by its
+// depositor, and that its proof of encryption is valid and bound to the record.
+func (record *Record) Verify(pk *tcpaillier.PubKey) error {
+	if record.Version != Version {
+		return fmt.Errorf("record version should be %d, but it is %d", Version, record.Version)
+	}
+	if record.Fingerprint != pk.Fingerprint() {
+		return fmt.Errorf("record %s: %w", record.ID, tcpaillier.ErrFingerprintMismatch)
+	}
+	if len(record.Depositor) != ed25519.PublicKeySize || record.Ciphertext == nil {
+		return fmt.Errorf("record %s is incomplete", record.ID)
+	}
+	if !ed25519.Verify(record.Depositor, record.digest(), record.Signature) {
+		return fmt.Errorf("record %s has an invalid signature", record.ID)
+	}
+	if !bytes.Equal(record.Ciphertext.Label, record.label()) {
+		return fmt.Errorf("record %s has A secret encrypted for another record", record.ID)
+	}
+	if err := record.Ciphertext.Verify(pk); err != nil {
+		return fmt.Errorf("record %s: %w", record.ID, err)
+	}
+	return nil
+}
+
+// NewRecovery returns A request to recover the secret of the record, whose decryption
+// shares are sealed to the recipient key of the requester. Holders can be nil if the
+// identities of the holders are not known.
+func NewRecovery(record *Record, requester string, to *recipient.PublicKey, reason string, holders map[uint8]ed25519.PublicKey) (rc *Recovery, err error) {
+	if to == nil {
+		err = fmt.Errorf("recipient key of the requester is required")
+		return
+	}
+	id, err := newID()
+	if err != nil {
+		return
+	}
+	rc = &Recovery{
+		ID:           id,
+		RecordID:     record.ID,
+		Requester:    requester,
+		RequesterKey: *to,
+		Reason:       reason,
+		Created:      time.Now().UTC(),
+		Holders:      holders,
+	}
+	return
+}
+
+// Approve approves the recovery with the key share signer, after checking the record. The
+// decryption share is sealed to the requester, and the approval is signed with the identity
+// key of the holder. The holder should check the policy of the record, the reason of the
+// recovery and the key of the requester before calling this function.
+func Approve(signer tcpaillier.ShareSigner, record *Record, rc *Recovery, identity ed25519.PrivateKey) (approval *Approval, err error) {
+	if err = record.Verify(signer.PublicKey()); err != nil {
+		return
+	}
+	if rc.RecordID != record.ID {
+		err = fmt.Errorf("recovery %s is for record %s, not for %s", rc.ID, rc.RecordID, record.ID)
+		return
+	}
+	share, proof, err := tcpaillier.PartialDecryptLabeled(signer, record.Ciphertext)
+	if err != nil {
+		return
+	}
+	sealed, err := recipient.Seal(record.Ciphertext.C, share, proof, &rc.RequesterKey)
+	if err != nil {
+		return
+	}
+	approval = &Approval{
+		RecoveryID: rc.ID,
+		Index:      share.Index,
+		Approver:   identity.Public().(ed25519.PublicKey),
+		Created:    time.Now().UTC(),
+		ShareHash:  shareHash(share),
+		Sealed:     sealed,
+	}
+	approval.Signature = ed25519.Sign(identity, approval.digest(rc))
+	return
+}
+
+// Add verifies an approval of the recovery and adds it to its audit trail, without its
+// sealed share. It returns an error if the approval is invalid, or if the holder of its
+// share already approved it.
+func (rc *Recovery) Add(approval *Approval) error {
+	if err := rc.verify(approval); err != nil {
+		return err
+	}
+	for _, other := range rc.Approvals {
+		if other.Index == approval.Index {
+			return fmt.Errorf("share %d already approved recovery %s", approval.Index, rc.ID)
+		}
+	}
+	trail := *approval
+	trail.Sealed = nil
+	rc.Approvals = append(rc.Approvals, &trail)
+	return nil
+}
+
+// Approvers returns the share indexes of the holders that approved the recovery, in the
+// order they approved it.
+func (rc *Recovery) Approvers() []uint8 {
+	indexes := make([]uint8, len(rc.Approvals))
+	for i, approval := range rc.Approvals {
+		indexes[i] = approval.Index
+	}
+	return indexes
+}
+
+// Ready returns true if the recovery has enough approvals to recover the secret.
+func (rc *Recovery) Ready(pk *tcpaillier.PubKey) bool {
+	return len(rc.Approvals) >= int(pk.K)
+}
+
+// Recover verifies the record and the audit trail of the recovery again, opens the sealed
+// shares of the approvals with the private key of the requester and recovers the secret with
+// the first K of them that match an approval of the trail. If there are not enough valid
+// shares, the error wraps A tcpaillier.InsufficientSharesError.
+func (rc *Recovery) Recover(pk *tcpaillier.PubKey, record *Record, priv *recipient.PrivateKey, approvals ...*Approval) (secret []byte, err error) {
+	if err = record.Verify(pk); err != nil {
+		return
+	}
+	if rc.RecordID != record.ID {
+		err = fmt.Errorf("recovery %s is for record %s, not for %s", rc.ID, rc.RecordID, record.ID)
+		return
+	}
+	trail := make(map[uint8]*Approval)
+	for _, approval := range rc.Approvals {
+		if _, ok := trail[approval.Index]; !ok && rc.verify(approval) == nil {
+			trail[approval.Index] = approval
+		}
+	}
+	var shares []*tcpaillier.DecryptionShare
+	used := make(map[uint8]bool)
+	for _, approval := range approvals {
+		if len(shares) == int(pk.K) {
+			break
+		}
+		if approval == nil || used[approval.Index] {
+			continue
+		}
+		signed, ok := trail[approval.Index]
+		if !ok {
+			continue
+		}
+		share, err := priv.Open(pk, record.Ciphertext.C, approval.Sealed)
+		if err != nil || share.Index != approval.Index || !bytes.Equal(shareHash(share), signed.ShareHash) {
+			continue
+		}
+		used[approval.Index] = true
+		shares = append(shares, share)
+	}
+	if len(shares) < int(pk.K) {
+		err = fmt.Errorf("recovery %s: %w", rc.ID, &tcpaillier.InsufficientSharesError{
+			Have: len(shares),
+			Need: int(pk.K),
+		})
+		return
+	}
+	m, err := pk.CombineShares(shares...)
+	if err != nil {
+		return
+	}
+	return decode(m)
+}
+
+// verify checks that the approval is for the recovery and that it is signed by its holder.
+func (rc *Recovery) verify(approval *Approval) error {
+	if approval == nil || len(approval.ShareHash) != sha256.Size {
+		return fmt.Errorf("approval is incomplete")
+	}
+	if approval.RecoveryID != rc.ID {
+		return fmt.Errorf("approval of share %d is for recovery %s, not for %s", approval.Index, approval.RecoveryID, rc.ID)
+	}
+	if rc.Holders != nil {
+		holder, ok := rc.Holders[approval.Index]
+		if !ok || !bytes.Equal(holder, approval.Approver) {
+			return fmt.Errorf("approval of share %d is not signed by its holder", approval.Index)
+		}
+	}
+	if len(approval.Approver) != ed25519.PublicKeySize || !ed25519.Verify(approval.Approver, approval.digest(rc), approval.Signature) {
+		return fmt.Errorf("approval of share %d has an invalid signature", approval.Index)
+	}
+	return nil
+}
+
+// label returns the label of the encrypted secret of the record.
+func (record *Record) label() []byte {
+	return digest(labelDomain,
+		[]byte(record.ID),
+		[]byte(record.Policy),
+		record.Depositor,
+	)
+}
+
+// digest returns the hash signed by the depositor of the record.
+func (record *Record) digest() []byte {
+	var label, c, b, w, z []byte
+	if lc := record.Ciphertext; lc != nil {
+		label, c = lc.Label, bytesOf(lc.C)
+		if lc.Proof != nil {
+			b, w, z = bytesOf(lc.Proof.B), bytesOf(lc.Proof.W), bytesOf(lc.Proof.Z)
+		}
+	}
+	return digest(recordDomain,
+		[]byte(strconv.Itoa(record.Version)),
+		[]byte(record.ID),
+		[]byte(record.Fingerprint),
+		[]byte(record.Policy),
+		record.Depositor,
+		[]byte(record.Created.UTC().Format(time.RFC3339Nano)),
+		label, c, b, w, z,
+	)
+}
+
+// digest returns the hash signed by the approver. It includes the request of the recovery,
+// so the approval cannot be moved to another one.
+func (approval *Approval) digest(rc *Recovery) []byte {
+	return digest(approvalDomain,
+		[]byte(rc.ID),
+		[]byte(rc.RecordID),
+		[]byte(rc.Requester),
+		rc.RequesterKey[:],
+		[]byte(rc.Reason),
+		[]byte{approval.Index},
+		approval.Approver,
+		[]byte(approval.Created.UTC().Format(time.RFC3339Nano)),
+		approval.ShareHash,
+	)
+}
+
+// shareHash returns the hash of the decryption share kept in the audit trail.
+func shareHash(share *tcpaillier.DecryptionShare) []byte {
+	hash := sha256.Sum256(share.Ci.Bytes())
+	return hash[:]
+}
+
+// digest hashes the domain and the fields, each one of them prefixed by its length.
+func digest(domain string, fields ...[]byte) []byte {
+	hash := sha256.New()
+	length := make([]byte, 4)
+	for _, field := range append([][]byte{[]byte(domain)}, fields...) {
+		binary.BigEndian.PutUint32(length, uint32(len(field)))
+		hash.Write(length)
+		hash.Write(field)
+	}
+	return hash.Sum(nil)
+}
+
+func bytesOf(x *big.Int) []byte {
+	if x == nil {
+		return nil
+	}
+	return x.Bytes()
+}
+
+// encode returns the secret as A message for the public key. It is prefixed by A byte with
+// value 1, so its leading zeros are kept.
+func encode(pk *tcpaillier.PubKey, secret []byte) (*big.Int, error) {
+	max := (pk.Cache().NToS.BitLen() - 1) / 8
+	if len(secret)+1 > max {
+		return nil, fmt.Errorf("secret should have at most %d bytes, but it has %d", max-1, len(secret))
+	}
+	return new(big.Int).SetBytes(append([]byte{1}, secret...)), nil
+}
+
+// decode returns the secret encoded in A message.
+func decode(m *big.Int) ([]byte, error) {
+	b := m.Bytes()
+	if len(b) == 0 || b[0] != 1 {
+		return nil, fmt.Errorf("decrypted value is not an escrowed secret")
+	}
+	return b[1:], nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, idSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
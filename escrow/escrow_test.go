@@ -0,0 +1,242 @@
+package escrow_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/escrow"
+	"github.com/niclabs/tcpaillier/recipient"
+)
+
+const k = 2
+const l = 3
+const s = 1
+
+const bitSize = 512
+
+// committee is A set of key share holders with their identity keys, and the recipient key
+// of the requester of the recoveries.
+type committee struct {
+	pk         *tcpaillier.PubKey
+	shares     []*tcpaillier.KeyShare
+	identities []ed25519.PrivateKey
+	holders    map[uint8]ed25519.PublicKey
+	requester  *recipient.PrivateKey
+}
+
+func newCommittee(t *testing.T) *committee {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	co := &committee{
+		pk:      pk,
+		shares:  shares,
+		holders: make(map[uint8]ed25519.PublicKey),
+	}
+	for _, share := range shares {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		co.identities = append(co.identities, priv)
+		co.holders[share.Index] = pub
+	}
+	if co.requester, err = recipient.GenerateKey(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	return co
+}
+
+// deposit escrows A random secret to the committee.
+func (co *committee) deposit(t *testing.T) ([]byte, *escrow.Record) {
+	_, depositor, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatalf("%v", err)
+	}
+	// A leading zero must be kept.
+	secret[0] = 0
+	record, err := escrow.Deposit(co.pk, secret, "recover only after two approvals from ops", depositor)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	return secret, record
+}
+
+func TestRecovery_Recover(t *testing.T) {
+	co := newCommittee(t)
+	secret, record := co.deposit(t)
+
+	// The record is sent as JSON to the holders.
+	b, err := json.Marshal(record)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var decoded escrow.Record
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := decoded.Verify(co.pk); err != nil {
+		t.Fatalf("decoded record should be valid: %v", err)
+	}
+
+	rc, err := escrow.NewRecovery(&decoded, "ops", &co.requester.PublicKey, "database restore", co.holders)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var approvals []*escrow.Approval
+	for _, i := range []int{2, 0} {
+		if rc.Ready(co.pk) {
+			t.Errorf("recovery should not be ready with %d approvals", len(rc.Approvals))
+		}
+		if _, err := rc.Recover(co.pk, &decoded, co.requester, approvals...); !errors.As(err, new(*tcpaillier.InsufficientSharesError)) {
+			t.Errorf("recovery with %d approvals should fail with InsufficientSharesError, but it fails with %v", len(rc.Approvals), err)
+		}
+		approval, err := escrow.Approve(co.shares[i], &decoded, rc, co.identities[i])
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if err := rc.Add(approval); err != nil {
+			t.Fatalf("%v", err)
+		}
+		if err := rc.Add(approval); err == nil {
+			t.Errorf("repeated approval should not be added")
+		}
+		approvals = append(approvals, approval)
+	}
+	if !rc.Ready(co.pk) {
+		t.Errorf("recovery should be ready with %d approvals", len(rc.Approvals))
+	}
+	if approvers := rc.Approvers(); len(approvers) != 2 || approvers[0] != 3 || approvers[1] != 1 {
+		t.Errorf("approvers should be [3 1], but they are %v", approvers)
+	}
+	// The audit trail does not have the decryption shares.
+	for _, approval := range rc.Approvals {
+		if approval.Sealed != nil {
+			t.Errorf("audit trail should not have the sealed share %d", approval.Index)
+		}
+	}
+	other, err := recipient.GenerateKey()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := rc.Recover(co.pk, &decoded, other, approvals...); !errors.As(err, new(*tcpaillier.InsufficientSharesError)) {
+		t.Errorf("recovery with another recipient key should fail with InsufficientSharesError, but it fails with %v", err)
+	}
+	recovered, err := rc.Recover(co.pk, &decoded, co.requester, approvals...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !bytes.Equal(recovered, secret) {
+		t.Errorf("recovered secret is not the escrowed one")
+	}
+}
+
+func TestApprove_invalid(t *testing.T) {
+	co := newCommittee(t)
+	_, record := co.deposit(t)
+	rc, err := escrow.NewRecovery(record, "ops", &co.requester.PublicKey, "database restore", co.holders)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	changed := *record
+	changed.Policy = "recover at any time"
+	if _, err := escrow.Approve(co.shares[0], &changed, rc, co.identities[0]); err == nil {
+		t.Errorf("holders should not approve A record with A changed policy")
+	}
+
+	// An attacker copies the encrypted secret to its own record with A lax policy.
+	_, attacker, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	forged := &escrow.Record{
+		Version:     escrow.Version,
+		ID:          "forged",
+		Fingerprint: record.Fingerprint,
+		Policy:      "recover at any time",
+		Created:     time.Now().UTC(),
+		Ciphertext:  record.Ciphertext,
+	}
+	forged.Sign(attacker)
+	if err := forged.Verify(co.pk); err == nil {
+		t.Errorf("record with the encrypted secret of another record should be invalid")
+	}
+	forgedRC, err := escrow.NewRecovery(forged, "attacker", &co.requester.PublicKey, "no reason", co.holders)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := escrow.Approve(co.shares[0], forged, forgedRC, co.identities[0]); err == nil {
+		t.Errorf("holders should not approve A record with the encrypted secret of another record")
+	}
+
+	// The holder of share 2 signs with the identity of share 1.
+	approval, err := escrow.Approve(co.shares[1], record, rc, co.identities[0])
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := rc.Add(approval); err == nil {
+		t.Errorf("approval signed by another holder should not be added")
+	}
+
+	approval, err = escrow.Approve(co.shares[1], record, rc, co.identities[1])
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	other, err := escrow.NewRecovery(record, "ops", &co.requester.PublicKey, "another reason", co.holders)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := other.Add(approval); err == nil {
+		t.Errorf("approval of another recovery should not be added")
+	}
+	approval.ShareHash[0] ^= 1
+	if err := rc.Add(approval); err == nil {
+		t.Errorf("approval with A modified share hash should not be added")
+	}
+}
+
+func TestRecovery_Recover_unsignedShare(t *testing.T) {
+	co := newCommittee(t)
+	_, record := co.deposit(t)
+	rc, err := escrow.NewRecovery(record, "ops", &co.requester.PublicKey, "database restore", co.holders)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var approvals []*escrow.Approval
+	for i := 0; i < k; i++ {
+		approval, err := escrow.Approve(co.shares[i], record, rc, co.identities[i])
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if err := rc.Add(approval); err != nil {
+			t.Fatalf("%v", err)
+		}
+		approvals = append(approvals, approval)
+	}
+	// A sealed share of A holder that is not in the audit trail is not used.
+	unsigned, err := escrow.Approve(co.shares[2], record, rc, co.identities[2])
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := rc.Recover(co.pk, record, co.requester, approvals[0], unsigned); !errors.As(err, new(*tcpaillier.InsufficientSharesError)) {
+		t.Errorf("recovery with A share not in the audit trail should fail with InsufficientSharesError, but it fails with %v", err)
+	}
+	// A sealed share moved to the approval of another holder is not used.
+	moved := *approvals[1]
+	moved.Sealed = unsigned.Sealed
+	if _, err := rc.Recover(co.pk, record, co.requester, approvals[0], &moved); !errors.As(err, new(*tcpaillier.InsufficientSharesError)) {
+		t.Errorf("recovery with A share moved to another approval should fail with InsufficientSharesError, but it fails with %v", err)
+	}
+}
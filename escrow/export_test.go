@@ -0,0 +1,9 @@
+package escrow
+
+import "crypto/ed25519"
+
+// Sign sets the depositor of the record and signs it, as Deposit does.
+func (record *Record) Sign(depositor ed25519.PrivateKey) {
+	record.Depositor = depositor.Public().(ed25519.PublicKey)
+	record.Signature = ed25519.Sign(depositor, record.digest())
+}
@@ -0,0 +1,105 @@
+package tcpaillier
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Fingerprint identifies A public key. It is the hex encoded SHA-256 hash of A canonical
+// encoding of N, V, Vi, L, K and S, so two public keys have the same fingerprint only if
+// those values are the same. An empty fingerprint means that it is unknown.
+type Fingerprint string
+
+// shortFingerprintSize is the number of hex digits of the short form of A fingerprint.
+const shortFingerprintSize = 16
+
+// Fingerprint returns the fingerprint of the public key. The values are encoded in this
+// order: N, V and every Vi as big endian byte strings prefixed by their length as A 32 bit
+// big endian integer, and then L, K and S as one byte each. The hash is computed with the
+// values returned by Cache when they are initialized, so the public key must not be
+// modified after that.
+func (pk *PubKey) Fingerprint() Fingerprint {
+	return pk.Cache().fingerprint
+}
+
+// fingerprint computes the fingerprint of the public key.
+func (pk *PubKey) fingerprint() Fingerprint {
+	hash := sha256.New()
+	length := make([]byte, 4)
+	for _, val := range append([]*big.Int{pk.N, pk.V}, pk.Vi...) {
+		var b []byte
+		if val != nil {
+			b = val.Bytes()
+		}
+		binary.BigEndian.PutUint32(length, uint32(len(b)))
+		hash.Write(length)
+		hash.Write(b)
+	}
+	hash.Write([]byte{pk.L, pk.K, pk.S})
+	return Fingerprint(hex.EncodeToString(hash.Sum(nil)))
+}
+
+// Short returns A human readable form of the fingerprint, with its first 16 hex digits
+// in groups of four, like "3f2a:91bc:0d4e:77a1".
+func (fp Fingerprint) Short() string {
+	s := string(fp)
+	if len(s) > shortFingerprintSize {
+		s = s[:shortFingerprintSize]
+	}
+	groups := make([]string, 0, (len(s)+3)/4)
+	for len(s) > 4 {
+		groups = append(groups, s[:4])
+		s = s[4:]
+	}
+	return strings.Join(append(groups, s), ":")
+}
+
+// checkFingerprint returns an error wrapping ErrFingerprintMismatch if the fingerprint of
+// A value is defined and it is not the one of the public key.
+func (pk *PubKey) checkFingerprint(name string, fp Fingerprint) error {
+	if fp == "" {
+		return nil
+	}
+	return checkFingerprint(name, fp, pk.Fingerprint())
+}
+
+// checkFingerprint returns an error wrapping ErrFingerprintMismatch if fp is defined and
+// it is not the fingerprint expected.
+func checkFingerprint(name string, fp, expected Fingerprint) error {
+	if fp != "" && fp != expected {
+		return fmt.Errorf("%s: %w: it belongs to %s, not to %s", name, ErrFingerprintMismatch, fp.Short(), expected.Short())
+	}
+	return nil
+}
+
+// Ciphertext is an encrypted value tagged with the fingerprint of the public key used to
+// encrypt it. It is useful to serialize encrypted values, so they are not used with
+// another key.
+type Ciphertext struct {
+	Fingerprint Fingerprint `json:",omitempty"`
+	C           *big.Int
+}
+
+// NewCiphertext returns the encrypted value c tagged with the fingerprint of the public key.
+func (pk *PubKey) NewCiphertext(c *big.Int) *Ciphertext {
+	return &Ciphertext{
+		Fingerprint: pk.Fingerprint(),
+		C:           c,
+	}
+}
+
+// Check checks that the encrypted value is valid and that it belongs to the public key. An
+// encrypted value without fingerprint is accepted if it is valid.
+func (ct *Ciphertext) Check(pk *PubKey) error {
+	if ct == nil {
+		return ErrNilCiphertext
+	}
+	if err := pk.checkFingerprint("encrypted value", ct.Fingerprint); err != nil {
+		return err
+	}
+	return pk.ValidateCiphertext(ct.C)
+}
@@ -0,0 +1,153 @@
+package tcpaillier_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+func TestPubKey_Fingerprint(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize/2, s, 3, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, otherPK, err := tcpaillier.NewKey(bitSize/2, s, 3, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	fp := pk.Fingerprint()
+	if len(fp) != 64 {
+		t.Errorf("fingerprint should have 64 hex digits, but it has %d", len(fp))
+	}
+	if short := fp.Short(); len(short) != 19 || short[:4] != string(fp[:4]) {
+		t.Errorf("short fingerprint %q is not A prefix of %q", short, fp)
+	}
+	b, err := json.Marshal(pk)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var decoded tcpaillier.PubKey
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if decoded.Fingerprint() != fp {
+		t.Errorf("fingerprint changed after encoding the public key")
+	}
+	if otherPK.Fingerprint() == fp {
+		t.Errorf("different public keys have the same fingerprint")
+	}
+	if shares[0].Fingerprint() != fp {
+		t.Errorf("key share fingerprint is not the one of its public key")
+	}
+}
+
+func TestPubKey_Fingerprint_parallel(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize/2, s, 3, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	c, _, err := pk.Encrypt(big.NewInt(42))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// The cached values are initialized before sharing the key, as the batch functions do.
+	pk.Cache()
+	var wg sync.WaitGroup
+	dss := make([]*tcpaillier.DecryptionShare, 8)
+	for i := range dss {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dss[i], _ = shares[i%len(shares)].PartialDecrypt(c)
+		}(i)
+	}
+	wg.Wait()
+	for i, ds := range dss {
+		if ds == nil || ds.Fingerprint != pk.Fingerprint() {
+			t.Errorf("decryption share %d should have the fingerprint of the public key", i)
+		}
+	}
+}
+
+func TestKeyShare_MarshalJSON(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize/2, s, 3, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, otherPK, err := tcpaillier.NewKey(bitSize/2, s, 3, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	b, err := json.Marshal(shares[0])
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var encoded map[string]json.RawMessage
+	if err := json.Unmarshal(b, &encoded); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if string(encoded["Fingerprint"]) != `"`+string(pk.Fingerprint())+`"` {
+		t.Errorf("encoded key share should have the fingerprint of its public key")
+	}
+	var decoded tcpaillier.KeyShare
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if decoded.Index != shares[0].Index || decoded.Si.Cmp(shares[0].Si) != 0 {
+		t.Errorf("decoded key share is not the encoded one")
+	}
+	encoded["Fingerprint"] = json.RawMessage(`"` + string(otherPK.Fingerprint()) + `"`)
+	if b, err = json.Marshal(encoded); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := json.Unmarshal(b, &decoded); !errors.Is(err, tcpaillier.ErrFingerprintMismatch) {
+		t.Errorf("key share with another fingerprint should fail with %v, but it fails with %v", tcpaillier.ErrFingerprintMismatch, err)
+	}
+}
+
+func TestFingerprint_Mismatch(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize/2, s, 3, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, otherPK, err := tcpaillier.NewKey(bitSize/2, s, 3, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	c, _, err := pk.Encrypt(twelve)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	ct := pk.NewCiphertext(c)
+	if err := ct.Check(pk); err != nil {
+		t.Errorf("encrypted value should belong to its public key: %v", err)
+	}
+	if err := ct.Check(otherPK); !errors.Is(err, tcpaillier.ErrFingerprintMismatch) {
+		t.Errorf("encrypted value checked with another key should fail with %v, but it fails with %v", tcpaillier.ErrFingerprintMismatch, err)
+	}
+	ds, zk, err := shares[0].PartialDecryptWithProof(c)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if ds.Fingerprint != pk.Fingerprint() {
+		t.Errorf("decryption share should have the fingerprint of its public key")
+	}
+	if err := zk.Verify(pk, c, ds); err != nil {
+		t.Errorf("decryption share proof should be valid: %v", err)
+	}
+	ds.Fingerprint = otherPK.Fingerprint()
+	if err := zk.Verify(pk, c, ds); !errors.Is(err, tcpaillier.ErrFingerprintMismatch) {
+		t.Errorf("verifying A share with another fingerprint should fail with %v, but it fails with %v", tcpaillier.ErrFingerprintMismatch, err)
+	}
+	ds2, err := shares[1].PartialDecrypt(c)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := pk.CombineShares(ds, ds2); !errors.Is(err, tcpaillier.ErrFingerprintMismatch) {
+		t.Errorf("combining A share with another fingerprint should fail with %v, but it fails with %v", tcpaillier.ErrFingerprintMismatch, err)
+	}
+}
@@ -3,6 +3,8 @@ package tcpaillier
 import (
 	"crypto/rand"
 	"math/big"
+
+	"github.com/niclabs/tcpaillier/internal/wipe"
 )
 
 // RandomInt is A function which generates A random big number.
@@ -32,3 +34,77 @@ func GenerateSafePrimes(bitLen int) (*big.Int, *big.Int, error) {
 		}
 	}
 }
+
+// defined returns true if none of the values is nil.
+func defined(vals ...*big.Int) bool {
+	for _, val := range vals {
+		if val == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// secrets is A list of intermediate secret values that are overwritten together.
+type secrets []*big.Int
+
+// add appends values to the list.
+func (sec *secrets) add(xs ...*big.Int) {
+	*sec = append(*sec, xs...)
+}
+
+// destroy overwrites every value of the list.
+func (sec *secrets) destroy() {
+	for _, x := range *sec {
+		wipe.Int(x)
+	}
+}
+
+// multiExp returns the product of every base to the power of the exponent with the same
+// index, modulo m. It uses simultaneous exponentiation with fixed windows, so all the
+// exponentiations share the same squarings. Negative exponents are computed using the
+// modular inverse of their base. It returns nil if one of these bases is not invertible.
+func multiExp(bases, exps []*big.Int, m *big.Int) *big.Int {
+	const window = 4
+	tables := make([][]*big.Int, len(bases))
+	absExps := make([]*big.Int, len(exps))
+	maxLen := 0
+	for i, base := range bases {
+		b := new(big.Int).Mod(base, m)
+		e := exps[i]
+		if e.Sign() < 0 {
+			if b.ModInverse(b, m) == nil {
+				return nil
+			}
+			e = new(big.Int).Neg(e)
+		}
+		absExps[i] = e
+		if e.BitLen() > maxLen {
+			maxLen = e.BitLen()
+		}
+		table := make([]*big.Int, 1<<window)
+		table[0] = big.NewInt(1)
+		table[1] = b
+		for j := 2; j < len(table); j++ {
+			table[j] = new(big.Int).Mul(table[j-1], b)
+			table[j].Mod(table[j], m)
+		}
+		tables[i] = table
+	}
+	result := big.NewInt(1)
+	for w := (maxLen+window-1)/window - 1; w >= 0; w-- {
+		for j := 0; j < window; j++ {
+			result.Mul(result, result).Mod(result, m)
+		}
+		for i, e := range absExps {
+			digit := uint(0)
+			for j := window - 1; j >= 0; j-- {
+				digit = digit<<1 | e.Bit(w*window+j)
+			}
+			if digit != 0 {
+				result.Mul(result, tables[i][digit]).Mod(result, m)
+			}
+		}
+	}
+	return result.Mod(result, m)
+}
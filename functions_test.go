@@ -83,3 +83,60 @@ func TestGenerateSafePrimes_keyGeneration(t *testing.T) {
 	}
 
 }
+
+func TestNewFixedKey_destroysSecrets(t *testing.T) {
+	p, p1, err := GenerateSafePrimes(utilsTestBitlen / 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	q, q1, err := GenerateSafePrimes(utilsTestBitlen / 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	params := &FixedParams{P: p, P1: p1, Q: q, Q1: q1}
+	var sec secrets
+	shares, _, err := newFixedKey(utilsTestBitlen, 2, 3, 2, params, &sec)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// m, its inverse, d and the polynomial coefficients should be among the secrets.
+	m := new(big.Int).Mul(p1, q1)
+	found := false
+	for _, x := range sec {
+		if x.Cmp(m) == 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("m should be among the intermediate secrets")
+	}
+	if len(sec) < 4+2 {
+		t.Errorf("there should be at least 6 intermediate secrets, but there are %d", len(sec))
+	}
+
+	words := make([][]big.Word, len(sec))
+	for i, x := range sec {
+		words[i] = x.Bits()[:cap(x.Bits())]
+	}
+	sec.destroy()
+	for i, xWords := range words {
+		for _, word := range xWords {
+			if word != 0 {
+				t.Errorf("intermediate secret %d was not overwritten", i)
+				break
+			}
+		}
+		if sec[i].Sign() != 0 {
+			t.Errorf("intermediate secret %d should be 0", i)
+		}
+	}
+	for _, share := range shares {
+		if share.Si.Sign() == 0 {
+			t.Errorf("key share %d should not be overwritten", share.Index)
+		}
+	}
+	if params.P.Cmp(p) != 0 || p.Sign() == 0 {
+		t.Errorf("params should not be overwritten")
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/niclabs/tcpaillier
 
 go 1.13
+
+require golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad
@@ -0,0 +1,342 @@
+// Package hybrid encrypts byte streams of any length for A threshold Paillier key. A
+// random AES-256 key is encrypted with the public key (KEM), and the payload is encrypted
+// with AES-GCM in chunks (DEM), so it can be streamed. The symmetric key is recovered by
+// the committee with the usual PartialDecrypt and CombineShares flow on Header.Key.
+//
+// A stream starts with A header, and it is followed by the encrypted chunks. Every chunk
+// has ChunkSize bytes of payload, except the last one, that can be shorter or empty. The
+// nonce of each chunk is its position and A flag marking the last one, and the header is
+// authenticated with every chunk, so chunks cannot be reordered, removed or moved to
+// another stream, and the stream cannot be truncated.
+package hybrid
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/internal/wipe"
+)
+
+// Version is the version of the format of the streams.
+const Version = 1
+
+// KeySize is the size in bytes of the symmetric key.
+const KeySize = 32
+
+// DefaultChunkSize is the payload size of the chunks written by NewWriter.
+const DefaultChunkSize = 64 * 1024
+
+// MaxChunkSize is the maximum payload size of A chunk. Readers reject streams with bigger
+// chunks, because A whole chunk is kept in memory.
+const MaxChunkSize = 16 * 1024 * 1024
+
+// MaxHeaderSize is the maximum size in bytes of an encoded header.
+const MaxHeaderSize = 1024 * 1024
+
+// magic identifies the streams of this package.
+var magic = []byte("TCPH")
+
+// ErrNotOpened is returned when an encrypted stream is read before its key is known.
+var ErrNotOpened = errors.New("symmetric key is not known yet")
+
+// Header is the first part of an encrypted stream. Key is the symmetric key encrypted with
+// the public key with the fingerprint provided.
+type Header struct {
+	Version     int
+	Fingerprint tcpaillier.Fingerprint
+	Key         *big.Int
+	ChunkSize   int
+}
+
+// Writer encrypts A stream. It complies with io.WriteCloser interface, and it must be
+// closed to write the last chunk.
+type Writer struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	aad    []byte
+	buf    []byte
+	n      int
+	out    []byte
+	chunk  uint64
+	closed bool
+	err    error
+}
+
+// Reader decrypts A stream. Its header can be read before the symmetric key is known, and
+// the payload can be read after Open or OpenKey are called. It complies with io.Reader
+// interface.
+type Reader struct {
+	Header *Header
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	aad    []byte
+	buf    []byte
+	plain  []byte
+	chunk  uint64
+	done   bool
+	err    error
+}
+
+// NewWriter writes the header of A new stream encrypted for the public key on w, and it
+// returns A writer for its payload. The payload is split on chunks of DefaultChunkSize bytes.
+func NewWriter(w io.Writer, pk *tcpaillier.PubKey) (*Writer, error) {
+	return NewWriterSize(w, pk, DefaultChunkSize)
+}
+
+// NewWriterSize is like NewWriter, but its chunks have chunkSize bytes of payload.
+func NewWriterSize(w io.Writer, pk *tcpaillier.PubKey, chunkSize int) (hw *Writer, err error) {
+	if chunkSize <= 0 || chunkSize > MaxChunkSize {
+		err = fmt.Errorf("chunk size should be between 1 and %d, but it is %d", MaxChunkSize, chunkSize)
+		return
+	}
+	if pk.Cache().NToS.BitLen() <= 8*KeySize {
+		err = fmt.Errorf("N^S should have more than %d bits to encrypt the symmetric key", 8*KeySize)
+		return
+	}
+	key := make([]byte, KeySize)
+	defer wipe.Bytes(key)
+	if _, err = rand.Read(key); err != nil {
+		return
+	}
+	m := new(big.Int).SetBytes(key)
+	defer wipe.Int(m)
+	c, _, err := pk.Encrypt(m)
+	if err != nil {
+		return
+	}
+	header := &Header{
+		Version:     Version,
+		Fingerprint: pk.Fingerprint(),
+		Key:         c,
+		ChunkSize:   chunkSize,
+	}
+	aad, err := json.Marshal(header)
+	if err != nil {
+		return
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return
+	}
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(aad)))
+	for _, b := range [][]byte{magic, length, aad} {
+		if _, err = w.Write(b); err != nil {
+			return
+		}
+	}
+	hw = &Writer{
+		w:    w,
+		aead: aead,
+		aad:  aad,
+		buf:  make([]byte, chunkSize),
+	}
+	return
+}
+
+// Write encrypts p. The chunks are written on the underlying writer only when they are full
+// and more payload is written, because the last chunk is marked as such.
+func (hw *Writer) Write(p []byte) (n int, err error) {
+	if hw.closed {
+		return 0, fmt.Errorf("write on closed stream")
+	}
+	if hw.err != nil {
+		return 0, hw.err
+	}
+	for len(p) > 0 {
+		if hw.n == len(hw.buf) {
+			if hw.err = hw.flush(false); hw.err != nil {
+				return n, hw.err
+			}
+		}
+		copied := copy(hw.buf[hw.n:], p)
+		hw.n += copied
+		n += copied
+		p = p[copied:]
+	}
+	return
+}
+
+// Close writes the last chunk. It does not close the underlying writer.
+func (hw *Writer) Close() error {
+	if hw.closed {
+		return hw.err
+	}
+	hw.closed = true
+	if hw.err == nil {
+		hw.err = hw.flush(true)
+	}
+	wipe.Bytes(hw.buf)
+	return hw.err
+}
+
+// flush encrypts the buffered payload and writes it as A chunk.
+func (hw *Writer) flush(last bool) error {
+	hw.out = hw.aead.Seal(hw.out[:0], chunkNonce(hw.chunk, last), hw.buf[:hw.n], hw.aad)
+	hw.chunk++
+	hw.n = 0
+	_, err := hw.w.Write(hw.out)
+	return err
+}
+
+// NewReader reads the header of an encrypted stream from r. Its payload can be read after
+// the symmetric key is recovered from Header.Key.
+func NewReader(r io.Reader) (hr *Reader, err error) {
+	br := bufio.NewReader(r)
+	prefix := make([]byte, len(magic)+4)
+	if _, err = io.ReadFull(br, prefix); err != nil {
+		err = fmt.Errorf("cannot read stream header: %v", err)
+		return
+	}
+	if string(prefix[:len(magic)]) != string(magic) {
+		err = fmt.Errorf("stream was not encrypted by this package")
+		return
+	}
+	length := binary.BigEndian.Uint32(prefix[len(magic):])
+	if length > MaxHeaderSize {
+		err = fmt.Errorf("stream header has %d bytes, but the maximum is %d", length, MaxHeaderSize)
+		return
+	}
+	aad := make([]byte, length)
+	if _, err = io.ReadFull(br, aad); err != nil {
+		err = fmt.Errorf("cannot read stream header: %v", err)
+		return
+	}
+	var header Header
+	if err = json.Unmarshal(aad, &header); err != nil {
+		err = fmt.Errorf("cannot decode stream header: %v", err)
+		return
+	}
+	if header.Version != Version {
+		err = fmt.Errorf("stream version should be %d, but it is %d", Version, header.Version)
+		return
+	}
+	if header.Key == nil {
+		err = fmt.Errorf("stream header has not an encrypted key")
+		return
+	}
+	if header.ChunkSize <= 0 || header.ChunkSize > MaxChunkSize {
+		err = fmt.Errorf("chunk size should be between 1 and %d, but it is %d", MaxChunkSize, header.ChunkSize)
+		return
+	}
+	hr = &Reader{
+		Header: &header,
+		r:      br,
+		aad:    aad,
+	}
+	return
+}
+
+// Open combines the decryption shares of Header.Key to recover the symmetric key of the
+// stream. The shares must belong to the public key of the stream.
+func (hr *Reader) Open(pk *tcpaillier.PubKey, shares ...*tcpaillier.DecryptionShare) error {
+	if pk.Fingerprint() != hr.Header.Fingerprint {
+		return fmt.Errorf("%w: stream was encrypted for %s", tcpaillier.ErrFingerprintMismatch, hr.Header.Fingerprint.Short())
+	}
+	key, err := pk.CombineShares(shares...)
+	if err != nil {
+		return err
+	}
+	defer wipe.Int(key)
+	return hr.OpenKey(key)
+}
+
+// OpenKey sets the symmetric key of the stream, that is the decryption of Header.Key. It
+// is useful when the key is recovered by other means, like A coordinator.
+func (hr *Reader) OpenKey(key *big.Int) error {
+	if key.Sign() < 0 || key.BitLen() > 8*KeySize {
+		return fmt.Errorf("decrypted key should have at most %d bytes", KeySize)
+	}
+	b := make([]byte, KeySize)
+	defer wipe.Bytes(b)
+	kb := key.Bytes()
+	copy(b[KeySize-len(kb):], kb)
+	wipe.Bytes(kb)
+	aead, err := newAEAD(b)
+	if err != nil {
+		return err
+	}
+	hr.aead = aead
+	hr.buf = make([]byte, hr.Header.ChunkSize+aead.Overhead())
+	return nil
+}
+
+// Read decrypts the payload of the stream. It returns an error if A chunk was modified, or
+// if the stream ends before its last chunk.
+func (hr *Reader) Read(p []byte) (n int, err error) {
+	if hr.aead == nil {
+		return 0, ErrNotOpened
+	}
+	for len(hr.plain) == 0 {
+		if hr.err != nil {
+			return 0, hr.err
+		}
+		if hr.done {
+			return 0, io.EOF
+		}
+		hr.err = hr.next()
+	}
+	n = copy(p, hr.plain)
+	hr.plain = hr.plain[n:]
+	return
+}
+
+// next reads and decrypts the next chunk. A chunk is the last one if it is shorter than the
+// others or if there is nothing after it.
+func (hr *Reader) next() error {
+	read, err := io.ReadFull(hr.r, hr.buf)
+	last := false
+	switch err {
+	case nil:
+		if _, err := hr.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	case io.ErrUnexpectedEOF:
+		last = true
+	case io.EOF:
+		return fmt.Errorf("stream is truncated: %w", io.ErrUnexpectedEOF)
+	default:
+		return err
+	}
+	plain, err := hr.aead.Open(hr.buf[:0], chunkNonce(hr.chunk, last), hr.buf[:read], hr.aad)
+	if err != nil {
+		if last {
+			return fmt.Errorf("chunk %d is invalid or the stream is truncated", hr.chunk)
+		}
+		return fmt.Errorf("chunk %d is invalid", hr.chunk)
+	}
+	hr.chunk++
+	hr.plain = plain
+	hr.done = last
+	return nil
+}
+
+// chunkNonce returns the nonce of A chunk: its position as A big endian integer, and A last
+// byte that is 1 only on the last chunk.
+func chunkNonce(chunk uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], chunk)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
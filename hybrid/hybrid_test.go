@@ -0,0 +1,147 @@
+package hybrid_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/hybrid"
+)
+
+const k = 2
+const l = 3
+const s = 1
+
+const bitSize = 512
+
+const chunkSize = 1000
+
+// encrypt encrypts msg for the public key with small chunks.
+func encrypt(t *testing.T, pk *tcpaillier.PubKey, msg []byte) []byte {
+	var buf bytes.Buffer
+	w, err := hybrid.NewWriterSize(&buf, pk, chunkSize)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// It is written in pieces that are not aligned with the chunks.
+	for len(msg) > 0 {
+		n := 333
+		if n > len(msg) {
+			n = len(msg)
+		}
+		if _, err := w.Write(msg[:n]); err != nil {
+			t.Fatalf("%v", err)
+		}
+		msg = msg[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	return buf.Bytes()
+}
+
+// decrypt decrypts A stream with the first K key shares.
+func decrypt(shares []*tcpaillier.KeyShare, pk *tcpaillier.PubKey, stream []byte) ([]byte, error) {
+	r, err := hybrid.NewReader(bytes.NewReader(stream))
+	if err != nil {
+		return nil, err
+	}
+	dss := make([]*tcpaillier.DecryptionShare, k)
+	for i, share := range shares[:k] {
+		if dss[i], err = share.PartialDecrypt(r.Header.Key); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.Open(pk, dss...); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func TestWriter(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for _, size := range []int{0, 1, chunkSize, 3*chunkSize + 7} {
+		msg := make([]byte, size)
+		if _, err := rand.Read(msg); err != nil {
+			t.Fatalf("%v", err)
+		}
+		stream := encrypt(t, pk, msg)
+		if size >= 16 && bytes.Contains(stream, msg) {
+			t.Errorf("stream of %d bytes contains the payload", size)
+		}
+		decrypted, err := decrypt(shares, pk, stream)
+		if err != nil {
+			t.Errorf("cannot decrypt stream of %d bytes: %v", size, err)
+			continue
+		}
+		if !bytes.Equal(decrypted, msg) {
+			t.Errorf("decrypted payload of %d bytes is not the encrypted one", size)
+		}
+	}
+}
+
+func TestReader_Invalid(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	msg := make([]byte, 3*chunkSize+7)
+	if _, err := rand.Read(msg); err != nil {
+		t.Fatalf("%v", err)
+	}
+	stream := encrypt(t, pk, msg)
+	headerSize := len(stream) - 4*(chunkSize+16) + chunkSize - 7
+
+	modified := append([]byte{}, stream...)
+	modified[headerSize+chunkSize] ^= 1
+	truncated := stream[:headerSize+2*(chunkSize+16)]
+	dropped := append(append([]byte{}, stream[:headerSize+chunkSize+16]...), stream[headerSize+2*(chunkSize+16):]...)
+
+	for name, invalid := range map[string][]byte{
+		"modified":  modified,
+		"truncated": truncated,
+		"dropped":   dropped,
+		"empty":     stream[:headerSize],
+	} {
+		if _, err := decrypt(shares, pk, invalid); err == nil {
+			t.Errorf("%s stream should not be decrypted", name)
+		}
+	}
+
+	r, err := hybrid.NewReader(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := r.Read(make([]byte, 10)); err != hybrid.ErrNotOpened {
+		t.Errorf("reading before opening should fail with %v, but it fails with %v", hybrid.ErrNotOpened, err)
+	}
+	_, otherPK, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := r.Open(otherPK); err == nil {
+		t.Errorf("stream should not be opened with another public key")
+	}
+}
+
+func TestWriter_damgardJurik(t *testing.T) {
+	// N has less bits than the symmetric key, so the key is decrypted only if the values
+	// greater than N are decrypted correctly.
+	shares, pk, err := tcpaillier.NewKey(bitSize/4, 3, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	msg := []byte("escrowed with s = 3")
+	decrypted, err := decrypt(shares, pk, encrypt(t, pk, msg))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !bytes.Equal(decrypted, msg) {
+		t.Errorf("decrypted payload is not the encrypted one")
+	}
+}
@@ -0,0 +1,25 @@
+// Package wipe overwrites secret values in memory, so they are not kept after they are used.
+package wipe
+
+import "math/big"
+
+// Int overwrites the words of A big integer, including the unused ones of its buffer, and
+// sets it to 0. Values copied by previous operations on it are not overwritten.
+func Int(x *big.Int) {
+	if x == nil {
+		return
+	}
+	words := x.Bits()
+	words = words[:cap(words)]
+	for i := range words {
+		words[i] = 0
+	}
+	x.SetInt64(0)
+}
+
+// Bytes overwrites A buffer with zeros.
+func Bytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,33 @@
+package wipe_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/niclabs/tcpaillier/internal/wipe"
+)
+
+func TestInt(t *testing.T) {
+	x, _ := new(big.Int).SetString("123456789012345678901234567890123456789", 10)
+	words := x.Bits()
+	wipe.Int(x)
+	if x.Sign() != 0 {
+		t.Errorf("wiped integer should be 0, but it is %s", x)
+	}
+	for i, word := range words[:cap(words)] {
+		if word != 0 {
+			t.Errorf("word %d of the wiped integer was not overwritten", i)
+		}
+	}
+	wipe.Int(nil)
+}
+
+func TestBytes(t *testing.T) {
+	b := []byte("secret")
+	wipe.Bytes(b)
+	for i, c := range b {
+		if c != 0 {
+			t.Errorf("byte %d of the wiped buffer was not overwritten", i)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Package keystore seals key shares to store them at rest. A sealed key share is encrypted
+// with AES-256-GCM under A key derived from A passphrase with Argon2id, or under A key
+// encryption key provided by the caller. Its metadata is authenticated with it.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/internal/wipe"
+	"golang.org/x/crypto/argon2"
+)
+
+// Version is the version of the sealed key share format.
+const Version = 1
+
+// KEKSize is the size in bytes of A key encryption key.
+const KEKSize = 32
+
+// saltSize is the size in bytes of the salt of the passphrase key derivation.
+const saltSize = 16
+
+// KDFParams are the parameters of the Argon2id key derivation from A passphrase.
+// Memory is in KiB.
+type KDFParams struct {
+	Name    string
+	Salt    []byte
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+// Maximum Argon2id parameters accepted when A key share is opened, so A crafted file cannot
+// make the key derivation use unbounded memory or time. Memory is in KiB.
+const (
+	MaxKDFTime    = 16
+	MaxKDFMemory  = 1024 * 1024
+	MaxKDFThreads = 64
+)
+
+// DefaultKDF are the Argon2id parameters used to seal key shares with A passphrase.
+var DefaultKDF = KDFParams{
+	Name:    "argon2id",
+	Time:    3,
+	Memory:  64 * 1024,
+	Threads: 4,
+}
+
+// Header is the metadata of A sealed key share. It is authenticated with the key share.
+// Fingerprint identifies the public key of the key share, and KDF is nil if the key share
+// was sealed with A key encryption key.
+type Header struct {
+	Version     int
+	Index       uint8
+	Fingerprint tcpaillier.Fingerprint
+	Created     time.Time
+	KDF         *KDFParams `json:",omitempty"`
+}
+
+// Sealed represents A key share encrypted with AES-256-GCM. Ciphertext is the encryption
+// of the JSON serialized key share, using the JSON serialized header as additional data.
+type Sealed struct {
+	Header
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// Seal encrypts A key share with A key derived from the passphrase, using the default
+// key derivation parameters.
+func Seal(share *tcpaillier.KeyShare, passphrase []byte) (sealed *Sealed, err error) {
+	kdf := DefaultKDF
+	kdf.Salt = make([]byte, saltSize)
+	if _, err = rand.Read(kdf.Salt); err != nil {
+		return
+	}
+	return seal(share, kdf.key(passphrase), &kdf)
+}
+
+// SealKEK encrypts A key share with A key encryption key of KEKSize bytes.
+func SealKEK(share *tcpaillier.KeyShare, kek []byte) (sealed *Sealed, err error) {
+	if len(kek) != KEKSize {
+		err = fmt.Errorf("key encryption key should have %d bytes, but it has %d", KEKSize, len(kek))
+		return
+	}
+	return seal(share, kek, nil)
+}
+
+// Open decrypts A key share sealed with the passphrase. If pk is not nil, the key share is
+// verified against it.
+func (sealed *Sealed) Open(passphrase []byte, pk *tcpaillier.PubKey) (share *tcpaillier.KeyShare, err error) {
+	kdf := sealed.KDF
+	if kdf == nil {
+		err = fmt.Errorf("key share was sealed with a key encryption key")
+		return
+	}
+	if err = kdf.validate(); err != nil {
+		return
+	}
+	return sealed.open(kdf.key(passphrase), pk)
+}
+
+// OpenKEK decrypts A key share sealed with the key encryption key. If pk is not nil, the key
+// share is verified against it.
+func (sealed *Sealed) OpenKEK(kek []byte, pk *tcpaillier.PubKey) (share *tcpaillier.KeyShare, err error) {
+	if sealed.KDF != nil {
+		err = fmt.Errorf("key share was sealed with a passphrase")
+		return
+	}
+	if len(kek) != KEKSize {
+		err = fmt.Errorf("key encryption key should have %d bytes, but it has %d", KEKSize, len(kek))
+		return
+	}
+	return sealed.open(kek, pk)
+}
+
+// Verify checks that the key share belongs to the public key, and that its verification value
+// on the public key matches its secret value.
+func Verify(share *tcpaillier.KeyShare, pk *tcpaillier.PubKey) error {
+	if share.PubKey == nil || share.Si == nil {
+		return fmt.Errorf("key share is incomplete")
+	}
+	if share.PubKey.Fingerprint() != pk.Fingerprint() {
+		return fmt.Errorf("%w: key share belongs to another public key", tcpaillier.ErrFingerprintMismatch)
+	}
+	if share.Index < 1 || share.Index > pk.L || int(pk.L) != len(pk.Vi) {
+		return fmt.Errorf("key share index %d is out of range", share.Index)
+	}
+	if pk.V == nil || pk.Delta == nil || pk.Delta.Cmp(new(big.Int).MulRange(1, int64(pk.L))) != 0 {
+		return fmt.Errorf("public key is incomplete")
+	}
+	// Vi = V^(delta*si) mod n^(s+1)
+	deltaSi := new(big.Int).Mul(pk.Delta, share.Si)
+	vi := new(big.Int).Exp(pk.V, deltaSi, pk.Cache().NToSPlusOne)
+	if vi.Cmp(pk.Vi[share.Index-1]) != 0 {
+		return fmt.Errorf("key share does not match its verification value")
+	}
+	return nil
+}
+
+func seal(share *tcpaillier.KeyShare, key []byte, kdf *KDFParams) (sealed *Sealed, err error) {
+	if share == nil || share.PubKey == nil || share.Si == nil {
+		err = fmt.Errorf("key share is incomplete")
+		return
+	}
+	sealed = &Sealed{
+		Header: Header{
+			Version:     Version,
+			Index:       share.Index,
+			Fingerprint: share.PubKey.Fingerprint(),
+			Created:     time.Now().UTC().Truncate(time.Second),
+			KDF:         kdf,
+		},
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return
+	}
+	header, err := json.Marshal(&sealed.Header)
+	if err != nil {
+		return
+	}
+	plaintext, err := json.Marshal(share)
+	if err != nil {
+		return
+	}
+	defer wipe.Bytes(plaintext)
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(sealed.Nonce); err != nil {
+		return
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, header)
+	return
+}
+
+func (sealed *Sealed) open(key []byte, pk *tcpaillier.PubKey) (share *tcpaillier.KeyShare, err error) {
+	if sealed.Version != Version {
+		err = fmt.Errorf("sealed key share version %d is not supported", sealed.Version)
+		return
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		err = fmt.Errorf("nonce should have %d bytes, but it has %d", aead.NonceSize(), len(sealed.Nonce))
+		return
+	}
+	header, err := json.Marshal(&sealed.Header)
+	if err != nil {
+		return
+	}
+	plaintext, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, header)
+	if err != nil {
+		err = fmt.Errorf("cannot decrypt key share: wrong key or modified file")
+		return
+	}
+	defer wipe.Bytes(plaintext)
+	var decoded tcpaillier.KeyShare
+	if err = json.Unmarshal(plaintext, &decoded); err != nil {
+		return
+	}
+	if decoded.PubKey == nil || decoded.Si == nil {
+		err = fmt.Errorf("sealed key share is incomplete")
+		return
+	}
+	if decoded.Index != sealed.Index || decoded.PubKey.Fingerprint() != sealed.Fingerprint {
+		err = fmt.Errorf("sealed key share does not match its metadata")
+		return
+	}
+	if pk != nil {
+		if err = Verify(&decoded, pk); err != nil {
+			return
+		}
+	}
+	share = &decoded
+	return
+}
+
+// validate checks that the key derivation parameters are defined and not greater than the
+// maximum ones.
+func (kdf *KDFParams) validate() error {
+	if kdf.Name != DefaultKDF.Name || len(kdf.Salt) == 0 || kdf.Time == 0 || kdf.Memory == 0 || kdf.Threads == 0 {
+		return fmt.Errorf("key derivation parameters are invalid")
+	}
+	if kdf.Time > MaxKDFTime || kdf.Memory > MaxKDFMemory || kdf.Threads > MaxKDFThreads {
+		return fmt.Errorf("key derivation parameters should be at most time %d, memory %d KiB and %d threads", MaxKDFTime, MaxKDFMemory, MaxKDFThreads)
+	}
+	return nil
+}
+
+// key derives A key encryption key from the passphrase.
+func (kdf *KDFParams) key(passphrase []byte) []byte {
+	return argon2.IDKey(passphrase, kdf.Salt, kdf.Time, kdf.Memory, kdf.Threads, KEKSize)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
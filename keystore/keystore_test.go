@@ -0,0 +1,99 @@
+package keystore_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/keystore"
+)
+
+const k = 2
+const l = 3
+const s = 1
+
+const bitSize = 256
+
+var passphrase = []byte("correct horse battery staple")
+
+func init() {
+	// The tests use cheaper parameters than the default ones.
+	keystore.DefaultKDF.Time = 1
+	keystore.DefaultKDF.Memory = 1024
+}
+
+func TestSeal(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	sealed, err := keystore.Seal(shares[1], passphrase)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if bytes.Contains(sealed.Ciphertext, []byte(shares[1].Si.String())) {
+		t.Errorf("sealed key share should not contain its secret value")
+	}
+	if sealed.Index != shares[1].Index {
+		t.Errorf("sealed key share index should be %d, but it is %d", shares[1].Index, sealed.Index)
+	}
+
+	// The key share should be opened after a serialization round trip.
+	b, err := json.Marshal(sealed)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var decoded keystore.Sealed
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("%v", err)
+	}
+	share, err := decoded.Open(passphrase, pk)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if share.Si.Cmp(shares[1].Si) != 0 || share.Index != shares[1].Index {
+		t.Errorf("opened key share is different from the sealed one")
+	}
+
+	if _, err := decoded.Open([]byte("wrong passphrase"), nil); err == nil {
+		t.Errorf("key share should not be opened with a wrong passphrase")
+	}
+	decoded.Index = 3
+	if _, err := decoded.Open(passphrase, nil); err == nil {
+		t.Errorf("key share with modified metadata should not be opened")
+	}
+}
+
+func TestSealKEK(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	kek := bytes.Repeat([]byte{7}, keystore.KEKSize)
+	sealed, err := keystore.SealKEK(shares[0], kek)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := sealed.Open(passphrase, nil); err == nil {
+		t.Errorf("key share sealed with a key encryption key should not be opened with a passphrase")
+	}
+	if _, err := sealed.OpenKEK(kek, pk); err != nil {
+		t.Errorf("%v", err)
+	}
+
+	// A share with a wrong secret value should not be verified.
+	wrong := *shares[0]
+	wrong.Si = new(big.Int).Add(wrong.Si, big.NewInt(1))
+	sealed, err = keystore.SealKEK(&wrong, kek)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := sealed.OpenKEK(kek, nil); err != nil {
+		t.Errorf("key share should be opened without verification: %v", err)
+	}
+	if _, err := sealed.OpenKEK(kek, pk); err == nil {
+		t.Errorf("key share with a wrong secret value should not be verified")
+	}
+}
@@ -0,0 +1,186 @@
+package keystore
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+// signer decrypts values partially with A sealed key share. The key share is opened only
+// while an operation runs, and its secret value is overwritten after that.
+type signer struct {
+	pk    *tcpaillier.PubKey
+	index uint8
+	key   []byte
+	load  func() (*Sealed, error)
+}
+
+// FileSigner decrypts values partially with A key share sealed on A file. The file is read
+// on every operation. It complies with tcpaillier.ShareSigner interface.
+type FileSigner struct {
+	signer
+}
+
+// MemorySigner decrypts values partially with A key share sealed in memory under A random
+// key encryption key. The key encryption key is kept in the same process memory, so the
+// sealing only defends against casual memory dumps that do not include it, and not against
+// anyone able to read the memory of the process. It complies with tcpaillier.ShareSigner
+// interface.
+type MemorySigner struct {
+	signer
+}
+
+// NewFileSigner returns A signer for the key share sealed on path with the passphrase. If pk
+// is not nil, the key share is verified against it.
+func NewFileSigner(path string, passphrase []byte, pk *tcpaillier.PubKey) (fs *FileSigner, err error) {
+	load := func() (*Sealed, error) {
+		return ReadFile(path)
+	}
+	sealed, err := load()
+	if err != nil {
+		return
+	}
+	if sealed.KDF == nil {
+		err = fmt.Errorf("key share on %s was sealed with a key encryption key", path)
+		return
+	}
+	if err = sealed.KDF.validate(); err != nil {
+		return
+	}
+	fs = &FileSigner{}
+	err = fs.init(sealed.KDF.key(passphrase), load, pk)
+	return
+}
+
+// NewFileSignerKEK returns A signer for the key share sealed on path with the key encryption
+// key. If pk is not nil, the key share is verified against it.
+func NewFileSignerKEK(path string, kek []byte, pk *tcpaillier.PubKey) (fs *FileSigner, err error) {
+	if len(kek) != KEKSize {
+		err = fmt.Errorf("key encryption key should have %d bytes, but it has %d", KEKSize, len(kek))
+		return
+	}
+	fs = &FileSigner{}
+	err = fs.init(append([]byte{}, kek...), func() (*Sealed, error) {
+		return ReadFile(path)
+	}, pk)
+	return
+}
+
+// NewMemorySigner returns A signer for the key share. The signer keeps A sealed copy of it
+// and its key encryption key, so the secret value is not kept in memory in plain form
+// between operations, but it can be recovered from A full dump of the process memory. The
+// caller should destroy the key share after calling this function.
+func NewMemorySigner(share *tcpaillier.KeyShare) (ms *MemorySigner, err error) {
+	kek := make([]byte, KEKSize)
+	if _, err = rand.Read(kek); err != nil {
+		return
+	}
+	sealed, err := SealKEK(share, kek)
+	if err != nil {
+		return
+	}
+	ms = &MemorySigner{}
+	err = ms.init(kek, func() (*Sealed, error) {
+		return sealed, nil
+	}, share.PubKey)
+	return
+}
+
+// ReadFile reads A sealed key share from A JSON file.
+func ReadFile(path string) (sealed *Sealed, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	sealed = &Sealed{}
+	if err = json.Unmarshal(b, sealed); err != nil {
+		err = fmt.Errorf("cannot decode %s: %v", path, err)
+	}
+	return
+}
+
+// WriteFile writes A sealed key share on A JSON file, that only its owner can read.
+func WriteFile(path string, sealed *Sealed) error {
+	b, err := json.Marshal(sealed)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// init opens the key share once to verify it and to keep its public values.
+func (sg *signer) init(key []byte, load func() (*Sealed, error), pk *tcpaillier.PubKey) error {
+	sg.key = key
+	sg.load = load
+	sealed, err := load()
+	if err != nil {
+		return err
+	}
+	share, err := sealed.open(key, pk)
+	if err != nil {
+		return err
+	}
+	defer share.Destroy()
+	sg.pk = share.PubKey
+	if pk != nil {
+		sg.pk = pk
+	}
+	sg.index = share.Index
+	sg.pk.Cache()
+	return nil
+}
+
+// PublicKey returns the public key of the key share.
+func (sg *signer) PublicKey() *tcpaillier.PubKey {
+	return sg.pk
+}
+
+// ShareIndex returns the index of the key share.
+func (sg *signer) ShareIndex() uint8 {
+	return sg.index
+}
+
+// PartialDecrypt decrypts partially the encrypted value with the sealed key share.
+func (sg *signer) PartialDecrypt(c *big.Int) (ds *tcpaillier.DecryptionShare, err error) {
+	share, err := sg.open()
+	if err != nil {
+		return
+	}
+	defer share.Destroy()
+	return share.PartialDecrypt(c)
+}
+
+// PartialDecryptProof returns A proof of the partial decryption of the encrypted value with
+// the sealed key share.
+func (sg *signer) PartialDecryptProof(c *big.Int, ds *tcpaillier.DecryptionShare) (zk *tcpaillier.DecryptShareZK, err error) {
+	share, err := sg.open()
+	if err != nil {
+		return
+	}
+	defer share.Destroy()
+	return share.PartialDecryptProof(c, ds)
+}
+
+// open opens the sealed key share, and checks that it is still the one the signer was
+// created with.
+func (sg *signer) open() (share *tcpaillier.KeyShare, err error) {
+	sealed, err := sg.load()
+	if err != nil {
+		return
+	}
+	if sealed.Index != sg.index || sealed.Fingerprint != sg.pk.Fingerprint() {
+		err = fmt.Errorf("sealed key share changed")
+		return
+	}
+	share, err = sealed.open(sg.key, nil)
+	if err != nil {
+		return
+	}
+	// The public key of the signer has its cached values.
+	share.PubKey = sg.pk
+	return
+}
@@ -0,0 +1,118 @@
+package keystore_test
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/keystore"
+)
+
+func TestSigners(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "share-1.json")
+	sealed, err := keystore.Seal(shares[0], passphrase)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := keystore.WriteFile(path, sealed); err != nil {
+		t.Fatalf("%v", err)
+	}
+	fileSigner, err := keystore.NewFileSigner(path, passphrase, pk)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	memorySigner, err := keystore.NewMemorySigner(shares[1])
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	signers := []tcpaillier.ShareSigner{fileSigner, memorySigner, shares[2]}
+
+	c, _, err := pk.Encrypt(big.NewInt(99))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var decShares []*tcpaillier.DecryptionShare
+	for _, signer := range signers[:2] {
+		ds, err := signer.PartialDecrypt(c)
+		if err != nil {
+			t.Fatalf("signer %d cannot decrypt partially: %v", signer.ShareIndex(), err)
+		}
+		zk, err := signer.PartialDecryptProof(c, ds)
+		if err != nil {
+			t.Fatalf("signer %d cannot prove its partial decryption: %v", signer.ShareIndex(), err)
+		}
+		if err := zk.Verify(pk, c, ds); err != nil {
+			t.Errorf("share of signer %d is invalid: %v", signer.ShareIndex(), err)
+		}
+		decShares = append(decShares, ds)
+	}
+	dec, err := pk.CombineShares(decShares...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if dec.Cmp(big.NewInt(99)) != 0 {
+		t.Errorf("decrypted value should be 99, but it is %s", dec)
+	}
+
+	// The file signer should notice that the sealed key share was replaced.
+	other, err := keystore.Seal(shares[2], passphrase)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := keystore.WriteFile(path, other); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := fileSigner.PartialDecrypt(c); err == nil {
+		t.Errorf("file signer should fail when its sealed key share is replaced")
+	}
+}
+
+func TestNewFileSigner_kdfLimits(t *testing.T) {
+	shares, _, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sealed, err := keystore.Seal(shares[0], passphrase)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// A crafted file asks for more memory or time than the maximum ones.
+	for _, kdf := range []keystore.KDFParams{
+		{Time: 1, Memory: keystore.MaxKDFMemory + 1, Threads: 1},
+		{Time: keystore.MaxKDFTime + 1, Memory: 1024, Threads: 1},
+		{Time: 1, Memory: 1024, Threads: keystore.MaxKDFThreads + 1},
+	} {
+		kdf.Name, kdf.Salt = sealed.KDF.Name, sealed.KDF.Salt
+		crafted := *sealed
+		crafted.KDF = &kdf
+		if _, err := crafted.Open(passphrase, nil); err == nil {
+			t.Errorf("key share with KDF parameters %+v should not be opened", kdf)
+		}
+		path := filepath.Join(dir, "crafted.json")
+		if err := keystore.WriteFile(path, &crafted); err != nil {
+			t.Fatalf("%v", err)
+		}
+		if _, err := keystore.NewFileSigner(path, passphrase, nil); err == nil {
+			t.Errorf("signer with KDF parameters %+v should not be created", kdf)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package tcpaillier
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// labeledDomain separates the challenges of labeled encryption proofs from the ones of
+// other proofs.
+const labeledDomain = "tcpaillier labeled encryption v1"
+
+// LabeledCiphertext is an encrypted value with A proof of knowledge of its message, bound
+// to A label and to the public key. A key share holder that only decrypts labeled values
+// with valid proofs cannot be used as A decryption oracle for values derived from other
+// ones, because nobody can prove the knowledge of their messages. This follows the TDH2
+// scheme of Shoup and Gennaro, with the Encryption ZKProof of Paillier as the proof of
+// validity. The label is public, and it can be used to state the conditions under which
+// the value may be decrypted.
+type LabeledCiphertext struct {
+	Fingerprint Fingerprint
+	Label       []byte
+	C           *big.Int
+	Proof       *EncryptZK
+}
+
+// EncryptLabeled encrypts A message and binds its proof of encryption to the label.
+func (pk *PubKey) EncryptLabeled(message *big.Int, label []byte) (lc *LabeledCiphertext, err error) {
+	r, err := pk.RandomModNToSPlusOneStar()
+	if err != nil {
+		return
+	}
+	c, err := pk.EncryptFixed(message, r)
+	if err != nil {
+		return
+	}
+	fp := pk.Fingerprint()
+	proof, err := pk.encryptProof(message, c, r, func(b *big.Int) *big.Int {
+		return labeledChallenge(fp, label, c, b)
+	})
+	if err != nil {
+		return
+	}
+	lc = &LabeledCiphertext{
+		Fingerprint: fp,
+		Label:       append([]byte{}, label...),
+		C:           c,
+		Proof:       proof,
+	}
+	return
+}
+
+// Verify checks that the labeled encrypted value belongs to the public key and that its
+// proof is valid for its label.
+func (lc *LabeledCiphertext) Verify(pk *PubKey) error {
+	if lc == nil {
+		return ErrNilCiphertext
+	}
+	fp := pk.Fingerprint()
+	if lc.Fingerprint != fp {
+		return fmt.Errorf("labeled encrypted value: %w", ErrFingerprintMismatch)
+	}
+	if err := pk.validateCiphertext("labeled encrypted value", lc.C); err != nil {
+		return err
+	}
+	zk := lc.Proof
+	if zk == nil || !defined(zk.B, zk.W, zk.Z) {
+		return fmt.Errorf("%w: proof is incomplete", ErrInvalidProof)
+	}
+	return zk.verify(pk, lc.C, labeledChallenge(fp, lc.Label, lc.C, zk.B))
+}
+
+// PartialDecryptLabeled decrypts partially A labeled encrypted value with the signer, and
+// returns the decryption share with its proof. It refuses to decrypt the value if its
+// proof is not valid.
+func PartialDecryptLabeled(signer ShareSigner, lc *LabeledCiphertext) (ds *DecryptionShare, zk *DecryptShareZK, err error) {
+	if err = lc.Verify(signer.PublicKey()); err != nil {
+		err = fmt.Errorf("refusing to decrypt: %w", err)
+		return
+	}
+	ds, err = signer.PartialDecrypt(lc.C)
+	if err != nil {
+		return
+	}
+	zk, err = signer.PartialDecryptProof(lc.C, ds)
+	return
+}
+
+// PartialDecryptLabeled decrypts partially A labeled encrypted value, if its proof is valid.
+func (ts *KeyShare) PartialDecryptLabeled(lc *LabeledCiphertext) (ds *DecryptionShare, zk *DecryptShareZK, err error) {
+	return PartialDecryptLabeled(ts, lc)
+}
+
+// labeledChallenge returns the challenge of A labeled Encryption ZKProof of c with
+// commitment b. Every variable length value is prefixed by its length.
+func labeledChallenge(fp Fingerprint, label []byte, c, b *big.Int) *big.Int {
+	hash := sha256.New()
+	length := make([]byte, 4)
+	for _, val := range [][]byte{[]byte(labeledDomain), []byte(fp), label, c.Bytes(), b.Bytes()} {
+		binary.BigEndian.PutUint32(length, uint32(len(val)))
+		hash.Write(length)
+		hash.Write(val)
+	}
+	return new(big.Int).SetBytes(hash.Sum(nil))
+}
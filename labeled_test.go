@@ -0,0 +1,102 @@
+package tcpaillier_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+func TestPubKey_EncryptLabeled(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	label := []byte("escrow: release after 2030-01-01")
+	lc, err := pk.EncryptLabeled(twelve, label)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := lc.Verify(pk); err != nil {
+		t.Fatalf("labeled encrypted value should be valid: %v", err)
+	}
+	dss := make([]*tcpaillier.DecryptionShare, k)
+	for i, share := range shares[:k] {
+		ds, zk, err := share.PartialDecryptLabeled(lc)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if err := zk.Verify(pk, lc.C, ds); err != nil {
+			t.Errorf("decryption share proof should be valid: %v", err)
+		}
+		dss[i] = ds
+	}
+	dec, err := pk.CombineShares(dss...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if dec.Cmp(twelve) != 0 {
+		t.Errorf("decrypted value should be %s, but it is %s", twelve, dec)
+	}
+}
+
+func TestPartialDecryptLabeled_Invalid(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize/2, s, 3, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, otherPK, err := tcpaillier.NewKey(bitSize/2, s, 3, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	label := []byte("label")
+	lc, err := pk.EncryptLabeled(twelve, label)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	relabeled := *lc
+	relabeled.Label = []byte("another label")
+
+	// A valid encrypted value is added to the labeled one, so its message changes.
+	c, _, err := pk.Encrypt(twelve)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	mauled := *lc
+	if mauled.C, err = pk.Add(lc.C, c); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// A valid unlabeled proof is not valid as A labeled one.
+	c, plainProof, err := pk.EncryptWithProof(twelve)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	unlabeled := tcpaillier.LabeledCiphertext{
+		Fingerprint: pk.Fingerprint(),
+		Label:       label,
+		C:           c,
+		Proof:       plainProof,
+	}
+	other := *lc
+	other.Fingerprint = otherPK.Fingerprint()
+	incomplete := *lc
+	incomplete.Proof = nil
+
+	for _, test := range []struct {
+		name string
+		lc   *tcpaillier.LabeledCiphertext
+		err  error
+	}{
+		{"relabeled", &relabeled, tcpaillier.ErrInvalidProof},
+		{"mauled", &mauled, tcpaillier.ErrInvalidProof},
+		{"unlabeled", &unlabeled, tcpaillier.ErrInvalidProof},
+		{"incomplete", &incomplete, tcpaillier.ErrInvalidProof},
+		{"other key", &other, tcpaillier.ErrFingerprintMismatch},
+		{"nil", nil, tcpaillier.ErrNilCiphertext},
+	} {
+		if _, _, err := shares[0].PartialDecryptLabeled(test.lc); !errors.Is(err, test.err) {
+			t.Errorf("decrypting %s value should fail with %v, but it fails with %v", test.name, test.err, err)
+		}
+	}
+}
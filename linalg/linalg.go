@@ -0,0 +1,300 @@
+// Package linalg implements homomorphic linear algebra over threshold Paillier
+// ciphertexts: encrypted vectors multiplied by plaintext matrices, inner products
+// with plaintext weights and weighted sums of encrypted vectors.
+//
+// All the operations are built on top of PubKey.MultiplyFixed and PubKey.Add, and
+// the work is split between a bounded number of goroutines.
+package linalg
+
+import (
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+var one = big.NewInt(1)
+
+// Evaluator computes linear operations over encrypted values using a public key.
+// If Workers is zero, it uses as many goroutines as CPUs are available.
+// If Proofs is true, every product of a ciphertext by a plaintext weight is
+// rerandomized and returned with a MulZK proof.
+type Evaluator struct {
+	PubKey  *tcpaillier.PubKey
+	Workers int
+	Proofs  bool
+}
+
+// Term represents the encrypted product of an input ciphertext by a plaintext weight.
+// Proof is only defined if the evaluator was configured to produce proofs.
+type Term struct {
+	Product *big.Int
+	Proof   *tcpaillier.MulZK
+}
+
+// Result represents the output of a linear operation. Values[j] is the j-th encrypted
+// output, and Terms[j] are the encrypted products that were added to obtain it. Terms
+// is only defined if the evaluator was configured to produce proofs.
+type Result struct {
+	Values []*big.Int
+	Terms  [][]*Term
+}
+
+// NewEvaluator returns an evaluator for the public key provided, using all the
+// available CPUs and without proofs.
+func NewEvaluator(pk *tcpaillier.PubKey) *Evaluator {
+	return &Evaluator{
+		PubKey: pk,
+	}
+}
+
+// MulMatrix multiplies an encrypted row vector by a plaintext matrix with as many rows
+// as elements the vector has. It returns an encrypted vector with as many elements as
+// columns the matrix has.
+func (ev *Evaluator) MulMatrix(vec []*big.Int, matrix [][]*big.Int) (res *Result, err error) {
+	if len(vec) == 0 {
+		err = fmt.Errorf("empty encrypted vector")
+		return
+	}
+	if len(matrix) != len(vec) {
+		err = fmt.Errorf("matrix should have %d rows, but it has %d", len(vec), len(matrix))
+		return
+	}
+	cols := len(matrix[0])
+	if cols == 0 {
+		err = fmt.Errorf("matrix has no columns")
+		return
+	}
+	for i, row := range matrix {
+		if len(row) != cols {
+			err = fmt.Errorf("matrix row %d should have %d columns, but it has %d", i, cols, len(row))
+			return
+		}
+	}
+	return ev.evaluate(cols, len(vec), func(j, i int) (*big.Int, *big.Int) {
+		return vec[i], matrix[i][j]
+	})
+}
+
+// InnerProduct returns the inner product of an encrypted vector with a vector of plaintext
+// weights of the same length. The result has only one value.
+func (ev *Evaluator) InnerProduct(vec, weights []*big.Int) (res *Result, err error) {
+	if len(vec) == 0 {
+		err = fmt.Errorf("empty encrypted vector")
+		return
+	}
+	if len(weights) != len(vec) {
+		err = fmt.Errorf("weights vector should have %d elements, but it has %d", len(vec), len(weights))
+		return
+	}
+	return ev.evaluate(1, len(vec), func(_, i int) (*big.Int, *big.Int) {
+		return vec[i], weights[i]
+	})
+}
+
+// WeightedSum returns the sum of a list of encrypted vectors of the same length, each one
+// multiplied by the plaintext weight with the same index.
+func (ev *Evaluator) WeightedSum(vectors [][]*big.Int, weights []*big.Int) (res *Result, err error) {
+	if len(vectors) == 0 {
+		err = fmt.Errorf("empty list of encrypted vectors")
+		return
+	}
+	if len(weights) != len(vectors) {
+		err = fmt.Errorf("weights vector should have %d elements, but it has %d", len(vectors), len(weights))
+		return
+	}
+	size := len(vectors[0])
+	if size == 0 {
+		err = fmt.Errorf("empty encrypted vector")
+		return
+	}
+	for k, vec := range vectors {
+		if len(vec) != size {
+			err = fmt.Errorf("encrypted vector %d should have %d elements, but it has %d", k, size, len(vec))
+			return
+		}
+	}
+	return ev.evaluate(size, len(vectors), func(j, k int) (*big.Int, *big.Int) {
+		return vectors[k][j], weights[k]
+	})
+}
+
+// VerifyMulMatrix verifies the proofs of a result returned by MulMatrix.
+func (ev *Evaluator) VerifyMulMatrix(vec []*big.Int, res *Result) error {
+	return ev.verify(res, len(vec), func(_, i int) *big.Int {
+		return vec[i]
+	})
+}
+
+// VerifyInnerProduct verifies the proofs of a result returned by InnerProduct.
+func (ev *Evaluator) VerifyInnerProduct(vec []*big.Int, res *Result) error {
+	return ev.verify(res, len(vec), func(_, i int) *big.Int {
+		return vec[i]
+	})
+}
+
+// VerifyWeightedSum verifies the proofs of a result returned by WeightedSum.
+func (ev *Evaluator) VerifyWeightedSum(vectors [][]*big.Int, res *Result) error {
+	return ev.verify(res, len(vectors), func(j, k int) *big.Int {
+		if j >= len(vectors[k]) {
+			return nil
+		}
+		return vectors[k][j]
+	})
+}
+
+// evaluate computes outputs values, where the j-th value is the sum of the inputs
+// ciphertexts returned by term(j, i) multiplied by their weights, for i between 0
+// and inputs - 1.
+func (ev *Evaluator) evaluate(outputs, inputs int, term func(j, i int) (c, alpha *big.Int)) (res *Result, err error) {
+	pk := ev.PubKey
+	nToS := pk.Cache().NToS
+
+	terms := make([][]*Term, outputs)
+	for j := range terms {
+		terms[j] = make([]*Term, inputs)
+	}
+	err = ev.parallel(outputs*inputs, func(n int) (err error) {
+		j, i := n/inputs, n%inputs
+		c, alpha := term(j, i)
+		alpha = new(big.Int).Mod(alpha, nToS)
+		t := &Term{}
+		if ev.Proofs {
+			t.Product, t.Proof, err = pk.MultiplyWithProof(c, alpha)
+		} else {
+			// The sums are rerandomized at the end, so we skip it here.
+			t.Product, err = pk.MultiplyFixed(c, alpha, one)
+		}
+		if err != nil {
+			return
+		}
+		terms[j][i] = t
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	res = &Result{
+		Values: make([]*big.Int, outputs),
+	}
+	err = ev.parallel(outputs, func(j int) (err error) {
+		products := make([]*big.Int, inputs)
+		for i, t := range terms[j] {
+			products[i] = t.Product
+		}
+		sum, err := pk.Add(products...)
+		if err != nil {
+			return
+		}
+		if !ev.Proofs {
+			r, err := pk.RandomModNToSPlusOneStar()
+			if err != nil {
+				return err
+			}
+			sum, err = pk.ReRand(sum, r)
+			if err != nil {
+				return err
+			}
+		}
+		res.Values[j] = sum
+		return
+	})
+	if err != nil {
+		res = nil
+		return
+	}
+	if ev.Proofs {
+		res.Terms = terms
+	}
+	return
+}
+
+// verify checks that every term of the result has A valid proof using the ciphertext
+// returned by input(j, i) as multiplied value, and that every value is the sum of its terms.
+func (ev *Evaluator) verify(res *Result, inputs int, input func(j, i int) *big.Int) error {
+	pk := ev.PubKey
+	if res.Terms == nil {
+		return fmt.Errorf("result does not have proofs")
+	}
+	if len(res.Terms) != len(res.Values) {
+		return fmt.Errorf("result has %d values but %d lists of terms", len(res.Values), len(res.Terms))
+	}
+	for j, terms := range res.Terms {
+		if len(terms) != inputs {
+			return fmt.Errorf("value %d should have %d terms, but it has %d", j, inputs, len(terms))
+		}
+	}
+	outputs := len(res.Values)
+	return ev.parallel(outputs*(inputs+1), func(n int) error {
+		j, i := n/(inputs+1), n%(inputs+1)
+		if i == inputs {
+			products := make([]*big.Int, inputs)
+			for k, t := range res.Terms[j] {
+				products[k] = t.Product
+			}
+			sum, err := pk.Add(products...)
+			if err != nil {
+				return err
+			}
+			if sum.Cmp(res.Values[j]) != 0 {
+				return fmt.Errorf("value %d is not the sum of its terms", j)
+			}
+			return nil
+		}
+		t := res.Terms[j][i]
+		c := input(j, i)
+		if c == nil || t == nil || t.Proof == nil {
+			return fmt.Errorf("term %d of value %d is not defined", i, j)
+		}
+		if err := t.Proof.Verify(pk, t.Product, c); err != nil {
+			return fmt.Errorf("term %d of value %d: %v", i, j, err)
+		}
+		return nil
+	})
+}
+
+// parallel executes fn(i) for i between 0 and n - 1 using at most ev.Workers goroutines.
+// It returns the first error found.
+func (ev *Evaluator) parallel(n int, fn func(i int) error) error {
+	workers := ev.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > n {
+		workers = n
+	}
+	jobs := make(chan int)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := fn(i); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	var err error
+loop:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case err = <-errs:
+			break loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+	if err == nil {
+		err = <-errs
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package linalg_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/linalg"
+)
+
+const k = 2
+const l = 3
+const s = 1
+
+const bitSize = 256
+
+func encryptVector(t *testing.T, pk *tcpaillier.PubKey, vals ...int64) []*big.Int {
+	vec := make([]*big.Int, len(vals))
+	for i, val := range vals {
+		c, _, err := pk.Encrypt(big.NewInt(val))
+		if err != nil {
+			t.Fatalf("cannot encrypt %d: %v", val, err)
+		}
+		vec[i] = c
+	}
+	return vec
+}
+
+func decryptVector(t *testing.T, shares []*tcpaillier.KeyShare, vec []*big.Int) []*big.Int {
+	pk := shares[0].PubKey
+	dec := make([]*big.Int, len(vec))
+	for i, c := range vec {
+		decryptShares := make([]*tcpaillier.DecryptionShare, len(shares))
+		for j, share := range shares {
+			ds, err := share.PartialDecrypt(c)
+			if err != nil {
+				t.Fatalf("share %d cannot decrypt partially value %d: %v", share.Index, i, err)
+			}
+			decryptShares[j] = ds
+		}
+		d, err := pk.CombineShares(decryptShares...)
+		if err != nil {
+			t.Fatalf("cannot combine shares of value %d: %v", i, err)
+		}
+		dec[i] = d
+	}
+	return dec
+}
+
+func matrix(rows ...[]int64) [][]*big.Int {
+	m := make([][]*big.Int, len(rows))
+	for i, row := range rows {
+		m[i] = vector(row...)
+	}
+	return m
+}
+
+func vector(vals ...int64) []*big.Int {
+	vec := make([]*big.Int, len(vals))
+	for i, val := range vals {
+		vec[i] = big.NewInt(val)
+	}
+	return vec
+}
+
+func checkVector(t *testing.T, dec []*big.Int, expected ...int64) {
+	if len(dec) != len(expected) {
+		t.Fatalf("length of result is %d instead of %d", len(dec), len(expected))
+	}
+	for i, val := range expected {
+		if dec[i].Cmp(big.NewInt(val)) != 0 {
+			t.Errorf("value %d is %s but should have been %d", i, dec[i], val)
+		}
+	}
+}
+
+func TestEvaluator_MulMatrix(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	vec := encryptVector(t, pk, 1, 2, 3)
+	m := matrix(
+		[]int64{1, 0},
+		[]int64{4, 5},
+		[]int64{7, 9},
+	)
+	for _, proofs := range []bool{false, true} {
+		ev := &linalg.Evaluator{PubKey: pk, Workers: 2, Proofs: proofs}
+		res, err := ev.MulMatrix(vec, m)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if proofs {
+			if err := ev.VerifyMulMatrix(vec, res); err != nil {
+				t.Errorf("error verifying proofs: %v", err)
+			}
+		}
+		checkVector(t, decryptVector(t, shares, res.Values), 30, 37)
+	}
+}
+
+func TestEvaluator_InnerProduct(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	vec := encryptVector(t, pk, 5, 6, 7)
+	ev := &linalg.Evaluator{PubKey: pk, Proofs: true}
+	res, err := ev.InnerProduct(vec, vector(2, -1, 3))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := ev.VerifyInnerProduct(vec, res); err != nil {
+		t.Errorf("error verifying proofs: %v", err)
+	}
+	checkVector(t, decryptVector(t, shares, res.Values), 25)
+
+	// A tampered value should not verify.
+	res.Values[0] = vec[0]
+	if err := ev.VerifyInnerProduct(vec, res); err == nil {
+		t.Errorf("tampered result should not be valid")
+	}
+}
+
+func TestEvaluator_WeightedSum(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	vectors := [][]*big.Int{
+		encryptVector(t, pk, 1, 2),
+		encryptVector(t, pk, 10, 20),
+	}
+	ev := linalg.NewEvaluator(pk)
+	res, err := ev.WeightedSum(vectors, vector(3, 2))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	checkVector(t, decryptVector(t, shares, res.Values), 23, 46)
+	if _, err := ev.WeightedSum(vectors, vector(1)); err == nil {
+		t.Errorf("weights with wrong length should return an error")
+	}
+}
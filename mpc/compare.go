@@ -0,0 +1,154 @@
+package mpc
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// StatisticalSecurity is the number of bits of statistical security of the masks used
+// to open values in the comparison protocols.
+const StatisticalSecurity = 40
+
+// LessThan returns an encryption of 1 if a < b, and an encryption of 0 otherwise, without
+// revealing a or b. Both a and b must be between 0 and 2^bits - 1.
+//
+// It uses the bit decomposition of A masked value: x = 2^bits + b - a - 1 is masked with
+// r = r' + 2^bits * R, where r' has random encrypted bits and R is statistically hiding.
+// After opening x + r, x mod 2^bits is computed with A bitwise comparison of its low bits
+// with r', and the result is the bit on position bits of x.
+func (p *Party) LessThan(ca, cb *big.Int, bits int) (lt *big.Int, err error) {
+	pk := p.Share.PubKey
+	nToS := pk.Cache().NToS
+	if bits < 1 {
+		err = fmt.Errorf("bits should be at least 1, but it is %d", bits)
+		return
+	}
+	// x + r must be lower than n^s, with at most 255 parties adding their masks.
+	if nToS.BitLen() <= bits+StatisticalSecurity+10 {
+		err = fmt.Errorf("N^s is too small to compare values of %d bits", bits)
+		return
+	}
+	twoToBits := new(big.Int).Lsh(one, uint(bits))
+
+	// x = 2^bits + b - a - 1
+	x, err := p.linear(new(big.Int).Sub(twoToBits, one), cb, one, ca, big.NewInt(-1))
+	if err != nil {
+		return
+	}
+
+	// r' = sum 2^i * r_i
+	lowBits, err := p.RandomBits(bits)
+	if err != nil {
+		return
+	}
+	// R = sum of R_p, where R_p has StatisticalSecurity random bits chosen by party p
+	contributions, err := p.commitBits(StatisticalSecurity)
+	if err != nil {
+		return
+	}
+	maskTerms := make([]*big.Int, 0, 2*(bits+len(contributions)*StatisticalSecurity))
+	for i, bit := range lowBits {
+		maskTerms = append(maskTerms, bit, new(big.Int).Lsh(one, uint(i)))
+	}
+	for _, highBits := range contributions {
+		for i, bit := range highBits {
+			maskTerms = append(maskTerms, bit, new(big.Int).Lsh(one, uint(bits+i)))
+		}
+	}
+	r, err := p.linear(nil, maskTerms...)
+	if err != nil {
+		return
+	}
+	xPlusR, err := p.linear(nil, x, one, r, one)
+	if err != nil {
+		return
+	}
+	opened, err := p.Open(xPlusR)
+	if err != nil {
+		return
+	}
+	// c' = (x + r) mod 2^bits
+	cLow := new(big.Int).Mod(opened[0], twoToBits)
+
+	u, err := p.bitwiseLessThan(cLow, lowBits)
+	if err != nil {
+		return
+	}
+
+	// x mod 2^bits = c' - r' + 2^bits * u
+	xLowTerms := []*big.Int{u, twoToBits}
+	for i, bit := range lowBits {
+		xLowTerms = append(xLowTerms, bit, new(big.Int).Neg(new(big.Int).Lsh(one, uint(i))))
+	}
+	xLow, err := p.linear(cLow, xLowTerms...)
+	if err != nil {
+		return
+	}
+
+	// a < b = (x - x mod 2^bits) / 2^bits
+	twoToBitsInv := new(big.Int).ModInverse(twoToBits, nToS)
+	return p.linear(nil, x, twoToBitsInv, xLow, new(big.Int).Neg(twoToBitsInv))
+}
+
+// Equal returns an encryption of 1 if a == b, and an encryption of 0 otherwise, without
+// revealing a or b. Both a and b must be between 0 and 2^bits - 1.
+func (p *Party) Equal(ca, cb *big.Int, bits int) (eq *big.Int, err error) {
+	lt, err := p.LessThan(ca, cb, bits)
+	if err != nil {
+		return
+	}
+	gt, err := p.LessThan(cb, ca, bits)
+	if err != nil {
+		return
+	}
+	// a == b = 1 - (a < b) - (b < a)
+	return p.linear(one, lt, big.NewInt(-1), gt, big.NewInt(-1))
+}
+
+// bitwiseLessThan returns an encryption of 1 if c < r, and an encryption of 0 otherwise.
+// c is public, and r is given as A list of encrypted bits, from the least significant one.
+//
+// c < r = sum (1 - c_i) * r_i * prod_{j > i} (1 - (c_j xor r_j))
+func (p *Party) bitwiseLessThan(c *big.Int, r []*big.Int) (lt *big.Int, err error) {
+	bits := len(r)
+	// eq_j = 1 - (c_j xor r_j), that is r_j if c_j = 1 and 1 - r_j otherwise
+	eqs := make([]*big.Int, bits)
+	for j, rj := range r {
+		if c.Bit(j) == 1 {
+			eqs[j] = rj
+		} else {
+			eqs[j], err = p.linear(one, rj, big.NewInt(-1))
+			if err != nil {
+				return
+			}
+		}
+	}
+	// prefix[i] = prod_{j > i} eq_j
+	prefix := make([]*big.Int, bits)
+	prefix[bits-1], err = p.linear(one)
+	if err != nil {
+		return
+	}
+	for i := bits - 2; i >= 0; i-- {
+		prefix[i], err = p.Mul(prefix[i+1], eqs[i+1])
+		if err != nil {
+			return
+		}
+	}
+	var as, bs []*big.Int
+	for i := 0; i < bits; i++ {
+		if c.Bit(i) == 0 {
+			as = append(as, r[i])
+			bs = append(bs, prefix[i])
+		}
+	}
+	terms, err := p.MulMany(as, bs)
+	if err != nil {
+		return
+	}
+	sum := make([]*big.Int, 0, 2*len(terms))
+	for _, term := range terms {
+		sum = append(sum, term, one)
+	}
+	return p.linear(nil, sum...)
+}
@@ -0,0 +1,131 @@
+package mpc_test
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/mpc"
+)
+
+const compareBits = 4
+
+// runParties runs protocol on every party over a local network, and returns the
+// encrypted value returned by the first party after checking that every party
+// returned the same one.
+func runParties(t *testing.T, shares []*tcpaillier.KeyShare, protocol func(p *mpc.Party) (*big.Int, error)) *big.Int {
+	// The public key is shared by the parties, so its cache is built before they start.
+	shares[0].Cache()
+	net := mpc.NewLocalNetwork(len(shares))
+	net.Timeout = time.Minute
+	results := make([]*big.Int, len(shares))
+	errs := make([]error, len(shares))
+	var wg sync.WaitGroup
+	for i, endpoint := range net.Endpoints() {
+		wg.Add(1)
+		go func(i int, endpoint mpc.Network) {
+			defer wg.Done()
+			party := &mpc.Party{Share: shares[i], Net: endpoint}
+			results[i], errs[i] = protocol(party)
+		}(i, endpoint)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("party %d failed: %v", i+1, err)
+		}
+		if results[i].Cmp(results[0]) != 0 {
+			t.Fatalf("party %d got a different result than party 1", i+1)
+		}
+	}
+	return results[0]
+}
+
+func TestParty_RandomBits(t *testing.T) {
+	shares, _, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	bits := runParties(t, shares, func(p *mpc.Party) (*big.Int, error) {
+		bits, err := p.RandomBits(8)
+		if err != nil {
+			return nil, err
+		}
+		decs, err := p.Open(bits...)
+		if err != nil {
+			return nil, err
+		}
+		packed := new(big.Int)
+		for i, dec := range decs {
+			if dec.Cmp(big.NewInt(1)) > 0 {
+				return nil, fmt.Errorf("random bit %d is %s", i, dec)
+			}
+			packed.SetBit(packed, i, uint(dec.Uint64()))
+		}
+		return packed, nil
+	})
+	if bits.BitLen() > 8 {
+		t.Errorf("random bits are not bits: %s", bits)
+	}
+}
+
+func TestParty_LessThan(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	cases := [][2]int64{{3, 5}, {5, 3}, {7, 7}, {0, 15}, {15, 0}}
+	for _, c := range cases {
+		ca, cb := encrypt(t, pk, c[0]), encrypt(t, pk, c[1])
+		lt := runParties(t, shares, func(p *mpc.Party) (*big.Int, error) {
+			return p.LessThan(ca, cb, compareBits)
+		})
+		expected := int64(0)
+		if c[0] < c[1] {
+			expected = 1
+		}
+		if dec := decrypt(t, shares, lt); dec.Cmp(big.NewInt(expected)) != 0 {
+			t.Errorf("%d < %d should be %d, but it is %s", c[0], c[1], expected, dec)
+		}
+	}
+}
+
+func TestParty_Equal(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	cases := [][2]int64{{9, 9}, {9, 10}}
+	for _, c := range cases {
+		ca, cb := encrypt(t, pk, c[0]), encrypt(t, pk, c[1])
+		eq := runParties(t, shares, func(p *mpc.Party) (*big.Int, error) {
+			return p.Equal(ca, cb, compareBits)
+		})
+		expected := int64(0)
+		if c[0] == c[1] {
+			expected = 1
+		}
+		if dec := decrypt(t, shares, eq); dec.Cmp(big.NewInt(expected)) != 0 {
+			t.Errorf("%d == %d should be %d, but it is %s", c[0], c[1], expected, dec)
+		}
+	}
+}
+
+func TestLocalNetwork_timeout(t *testing.T) {
+	net := mpc.NewLocalNetwork(2)
+	net.Timeout = 10 * time.Millisecond
+	endpoints := net.Endpoints()
+	msgs, err := endpoints[0].Exchange("hello")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if msgs[0] != "hello" || msgs[1] != nil {
+		t.Errorf("messages should have been [hello <nil>], but they are %v", msgs)
+	}
+	if _, err := endpoints[1].Exchange("late"); err == nil {
+		t.Errorf("late message should fail")
+	}
+}
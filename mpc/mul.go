@@ -0,0 +1,213 @@
+// Package mpc implements multi-party protocols run by the holders of the key shares
+// of A threshold Paillier key. Each protocol is A per-party state machine: every party
+// produces A message for each round, and it moves to the next round when it receives
+// the messages of the other parties for the current one.
+package mpc
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+var one = big.NewInt(1)
+var two = big.NewInt(2)
+
+// MulCommit is the message of A party in the first round of the multiplication protocol.
+// DB is the encryption of d*b, where d is A random value chosen by the party. Proof proves
+// that DB is the multiplication of the encryption of b by d, and Proof.CAlpha is the
+// encryption of d.
+type MulCommit struct {
+	Index uint8
+	DB    *big.Int
+	Proof *tcpaillier.MulZK
+}
+
+// MulDecrypt is the message of A party in the second round of the multiplication protocol.
+// It contains its partial decryption of the encryption of a+d, where d is the sum of the
+// random values of all the parties.
+type MulDecrypt struct {
+	Index uint8
+	Share *tcpaillier.DecryptionShare
+	Proof *tcpaillier.DecryptShareZK
+}
+
+// mulState represents the round A multiplication is on.
+type mulState int
+
+const (
+	mulStarted mulState = iota
+	mulCommitted
+	mulDecrypted
+	mulFinished
+)
+
+// Mul is the state of A party in the multiplication protocol of Cramer, Damgård and Nielsen,
+// that returns an encryption of a*b from the encryptions of a and b:
+//
+// 1. Each party i chooses A random d_i, and sends E(d_i) and E(d_i*b) with A MulZK proof.
+//
+// 2. Each party computes E(a+d) = E(a) * prod E(d_i) with the valid commits, and sends
+// its partial decryption of it with A proof.
+//
+// 3. Each party combines K valid decryption shares to get a+d, and computes
+// E(a*b) = E(b)^(a+d) * prod E(d_i*b)^(-1).
+//
+// All the honest parties receiving the same messages get the same encryption of a*b.
+type Mul struct {
+	share   *tcpaillier.KeyShare
+	ca, cb  *big.Int
+	state   mulState
+	commits []*MulCommit
+	masked  *big.Int
+}
+
+// NewMul returns the state of A party in A multiplication of the encrypted values ca and cb.
+func NewMul(share *tcpaillier.KeyShare, ca, cb *big.Int) *Mul {
+	return &Mul{
+		share: share,
+		ca:    ca,
+		cb:    cb,
+	}
+}
+
+// Commit returns the message of the party for the first round.
+func (m *Mul) Commit() (commit *MulCommit, err error) {
+	if m.state != mulStarted {
+		err = fmt.Errorf("multiplication is not on its first round")
+		return
+	}
+	// a+d is decrypted modulo n^s, so d must be uniform modulo n^s to hide all of a.
+	d, err := m.share.RandomModNToS()
+	if err != nil {
+		return
+	}
+	db, proof, err := m.share.MultiplyWithProof(m.cb, d)
+	if err != nil {
+		return
+	}
+	commit = &MulCommit{
+		Index: m.share.Index,
+		DB:    db,
+		Proof: proof,
+	}
+	m.state = mulCommitted
+	return
+}
+
+// Decrypt receives the first round messages of all the parties, including the one of this
+// party, and returns the message of this party for the second round. Commits with invalid
+// proofs or indexes are ignored, and only the first valid commit of each index is used.
+func (m *Mul) Decrypt(commits []*MulCommit) (dec *MulDecrypt, err error) {
+	if m.state != mulCommitted {
+		err = fmt.Errorf("multiplication is not on its second round")
+		return
+	}
+	pk := m.share.PubKey
+	valid := make(map[uint8]*MulCommit)
+	for _, commit := range commits {
+		if commit == nil || commit.DB == nil || commit.Proof == nil {
+			continue
+		}
+		if commit.Index < 1 || commit.Index > pk.L {
+			continue
+		}
+		if _, ok := valid[commit.Index]; ok {
+			continue
+		}
+		if commit.Proof.Verify(pk, commit.DB, m.cb) != nil {
+			continue
+		}
+		valid[commit.Index] = commit
+	}
+	if _, ok := valid[m.share.Index]; !ok {
+		err = fmt.Errorf("commit of this party is missing or invalid")
+		return
+	}
+	m.commits = make([]*MulCommit, 0, len(valid))
+	for _, commit := range valid {
+		m.commits = append(m.commits, commit)
+	}
+	sort.Slice(m.commits, func(i, j int) bool {
+		return m.commits[i].Index < m.commits[j].Index
+	})
+
+	// E(a+d) = E(a) * prod E(d_i)
+	masked := []*big.Int{m.ca}
+	for _, commit := range m.commits {
+		masked = append(masked, commit.Proof.CAlpha)
+	}
+	m.masked, err = pk.Add(masked...)
+	if err != nil {
+		return
+	}
+	share, proof, err := m.share.PartialDecryptWithProof(m.masked)
+	if err != nil {
+		return
+	}
+	dec = &MulDecrypt{
+		Index: m.share.Index,
+		Share: share,
+		Proof: proof,
+	}
+	m.state = mulDecrypted
+	return
+}
+
+// Finish receives the second round messages of the parties, and returns the encryption of a*b.
+// Decryption shares with invalid proofs are ignored, and it fails if there are less than K
+// valid ones.
+func (m *Mul) Finish(decs []*MulDecrypt) (cab *big.Int, err error) {
+	if m.state != mulDecrypted {
+		err = fmt.Errorf("multiplication is not on its last round")
+		return
+	}
+	pk := m.share.PubKey
+	var shares []*tcpaillier.DecryptionShare
+	seen := make(map[uint8]struct{})
+	for _, dec := range decs {
+		if dec == nil || dec.Share == nil || dec.Proof == nil || dec.Share.Index != dec.Index {
+			continue
+		}
+		if _, ok := seen[dec.Index]; ok {
+			continue
+		}
+		if dec.Proof.Verify(pk, m.masked, dec.Share) != nil {
+			continue
+		}
+		seen[dec.Index] = struct{}{}
+		shares = append(shares, dec.Share)
+	}
+	aPlusD, err := pk.CombineShares(shares...)
+	if err != nil {
+		return
+	}
+
+	// E(a*b) = E(b)^(a+d) * (prod E(d_i*b))^(-1)
+	dbs := make([]*big.Int, len(m.commits))
+	for i, commit := range m.commits {
+		dbs[i] = commit.DB
+	}
+	db, err := pk.Add(dbs...)
+	if err != nil {
+		return
+	}
+	nToSPlusOne := pk.Cache().NToSPlusOne
+	dbInv := new(big.Int).ModInverse(db, nToSPlusOne)
+	if dbInv == nil {
+		err = fmt.Errorf("encryption of d*b is not invertible")
+		return
+	}
+	cbToAPlusD, err := pk.MultiplyFixed(m.cb, aPlusD, one)
+	if err != nil {
+		return
+	}
+	cab, err = pk.Add(cbToAPlusD, dbInv)
+	if err != nil {
+		return
+	}
+	m.state = mulFinished
+	return
+}
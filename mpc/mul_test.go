@@ -0,0 +1,200 @@
+package mpc_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/mpc"
+)
+
+const k = 2
+const l = 3
+const s = 1
+
+const bitSize = 256
+
+func encrypt(t *testing.T, pk *tcpaillier.PubKey, msg int64) *big.Int {
+	c, _, err := pk.Encrypt(big.NewInt(msg))
+	if err != nil {
+		t.Fatalf("cannot encrypt %d: %v", msg, err)
+	}
+	return c
+}
+
+func decrypt(t *testing.T, shares []*tcpaillier.KeyShare, c *big.Int) *big.Int {
+	decryptShares := make([]*tcpaillier.DecryptionShare, len(shares))
+	for i, share := range shares {
+		ds, err := share.PartialDecrypt(c)
+		if err != nil {
+			t.Fatalf("share %d is not able to decrypt partially the message: %v", share.Index, err)
+		}
+		decryptShares[i] = ds
+	}
+	dec, err := shares[0].CombineShares(decryptShares...)
+	if err != nil {
+		t.Fatalf("cannot combine shares: %v", err)
+	}
+	return dec
+}
+
+// runMul runs the multiplication protocol in memory, where every party receives the
+// messages of all the parties. tamper can modify the commits before they are delivered,
+// and it returns the index of the cheating party, which stops after the first round.
+func runMul(t *testing.T, shares []*tcpaillier.KeyShare, ca, cb *big.Int, tamper func([]*mpc.MulCommit) int) []*big.Int {
+	parties := make([]*mpc.Mul, len(shares))
+	commits := make([]*mpc.MulCommit, len(shares))
+	for i, share := range shares {
+		parties[i] = mpc.NewMul(share, ca, cb)
+		commit, err := parties[i].Commit()
+		if err != nil {
+			t.Fatalf("party %d cannot commit: %v", share.Index, err)
+		}
+		commits[i] = commit
+	}
+	cheater := -1
+	if tamper != nil {
+		cheater = tamper(commits)
+	}
+	var decs []*mpc.MulDecrypt
+	var honest []*mpc.Mul
+	for i, party := range parties {
+		if i == cheater {
+			continue
+		}
+		dec, err := party.Decrypt(commits)
+		if err != nil {
+			t.Fatalf("party %d cannot decrypt: %v", i+1, err)
+		}
+		decs = append(decs, dec)
+		honest = append(honest, party)
+	}
+	results := make([]*big.Int, len(honest))
+	for i, party := range honest {
+		cab, err := party.Finish(decs)
+		if err != nil {
+			t.Fatalf("party %d cannot finish: %v", i+1, err)
+		}
+		results[i] = cab
+	}
+	return results
+}
+
+func TestMul(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	ca, cb := encrypt(t, pk, 12), encrypt(t, pk, 25)
+	results := runMul(t, shares, ca, cb, nil)
+	for i, cab := range results {
+		if cab.Cmp(results[0]) != 0 {
+			t.Errorf("party %d got a different result than party 1", i+1)
+		}
+	}
+	if dec := decrypt(t, shares, results[0]); dec.Cmp(big.NewInt(300)) != 0 {
+		t.Errorf("decrypted value is %s instead of 300", dec)
+	}
+}
+
+func TestMul_damgardJurik(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, 2, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// a has A high digit of 7 in base N.
+	a := new(big.Int).Mul(pk.N, big.NewInt(7))
+	a.Add(a, big.NewInt(5))
+	ca, _, err := pk.Encrypt(a)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	cb := encrypt(t, pk, 3)
+	parties := make([]*mpc.Mul, len(shares))
+	commits := make([]*mpc.MulCommit, len(shares))
+	for i, share := range shares {
+		parties[i] = mpc.NewMul(share, ca, cb)
+		if commits[i], err = parties[i].Commit(); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	decs := make([]*mpc.MulDecrypt, len(parties))
+	dss := make([]*tcpaillier.DecryptionShare, len(parties))
+	for i, party := range parties {
+		if decs[i], err = party.Decrypt(commits); err != nil {
+			t.Fatalf("%v", err)
+		}
+		dss[i] = decs[i].Share
+	}
+	// a+d is public, so its high digit must not reveal the one of a.
+	masked, err := pk.CombineShares(dss...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if high := new(big.Int).Div(masked, pk.N); high.Cmp(big.NewInt(7)) == 0 || high.Cmp(big.NewInt(8)) == 0 {
+		t.Errorf("high digit of a+d should be random, but it is %s", high)
+	}
+	cab, err := parties[0].Finish(decs)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := new(big.Int).Mul(a, big.NewInt(3))
+	if dec := decrypt(t, shares, cab); dec.Cmp(expected) != 0 {
+		t.Errorf("decrypted value is %s instead of %s", dec, expected)
+	}
+}
+
+func TestMul_invalidCommit(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	ca, cb := encrypt(t, pk, 7), encrypt(t, pk, 6)
+	// Party 3 sends a commit that does not match its proof, so it is ignored by everyone.
+	results := runMul(t, shares, ca, cb, func(commits []*mpc.MulCommit) int {
+		commits[2].DB = encrypt(t, pk, 1)
+		return 2
+	})
+	if dec := decrypt(t, shares, results[0]); dec.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("decrypted value is %s instead of 42", dec)
+	}
+}
+
+func TestMul_repeatedCommit(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	ca, cb := encrypt(t, pk, 7), encrypt(t, pk, 6)
+	// Party 3 replays the commit of party 1 instead of sending its own, so the multiplication
+	// is done with the commits of parties 1 and 2.
+	results := runMul(t, shares, ca, cb, func(commits []*mpc.MulCommit) int {
+		commits[2] = commits[0]
+		return 2
+	})
+	for i, cab := range results {
+		if cab.Cmp(results[0]) != 0 {
+			t.Errorf("party %d got a different result than party 1", i+1)
+		}
+	}
+	if dec := decrypt(t, shares, results[0]); dec.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("decrypted value is %s instead of 42", dec)
+	}
+}
+
+func TestMul_outOfOrder(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	m := mpc.NewMul(shares[0], encrypt(t, pk, 1), encrypt(t, pk, 2))
+	if _, err := m.Decrypt(nil); err == nil {
+		t.Errorf("decrypt should fail before commit")
+	}
+	if _, err := m.Commit(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := m.Commit(); err == nil {
+		t.Errorf("commit should fail when called twice")
+	}
+}
@@ -0,0 +1,108 @@
+package mpc
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Network represents the channel A party uses to talk with the other parties of A protocol.
+// Protocols run in synchronous rounds: on each round, every party sends one message to
+// all the parties and receives the messages the others sent on the same round.
+type Network interface {
+	// Exchange sends msg to all the parties and returns the messages of every party for
+	// the current round, including msg, in the same order for all the parties. The message
+	// of A party that did not answer is nil.
+	Exchange(msg interface{}) ([]interface{}, error)
+}
+
+// LocalNetwork is A simulated network between parties on the same process.
+// If Timeout is not zero, A round finishes after that time even if some parties
+// did not send their messages, and their messages are nil.
+type LocalNetwork struct {
+	Timeout time.Duration
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	parties int
+	rounds  map[int]*localRound
+}
+
+// localRound contains the messages of A round of A local network.
+type localRound struct {
+	msgs     []interface{}
+	received int
+	closed   bool
+	read     int
+}
+
+// localEndpoint is the view of A local network of one of its parties.
+type localEndpoint struct {
+	net   *LocalNetwork
+	id    int
+	round int
+}
+
+// NewLocalNetwork returns A local network for the number of parties provided.
+func NewLocalNetwork(parties int) *LocalNetwork {
+	net := &LocalNetwork{
+		parties: parties,
+		rounds:  make(map[int]*localRound),
+	}
+	net.cond = sync.NewCond(&net.mutex)
+	return net
+}
+
+// Endpoints returns the networks of every party, in order.
+func (net *LocalNetwork) Endpoints() []Network {
+	endpoints := make([]Network, net.parties)
+	for i := range endpoints {
+		endpoints[i] = &localEndpoint{
+			net: net,
+			id:  i,
+		}
+	}
+	return endpoints
+}
+
+// Exchange sends the message to the other endpoints of the local network.
+func (ep *localEndpoint) Exchange(msg interface{}) ([]interface{}, error) {
+	net := ep.net
+	net.mutex.Lock()
+	defer net.mutex.Unlock()
+
+	round, ok := net.rounds[ep.round]
+	if !ok {
+		round = &localRound{
+			msgs: make([]interface{}, net.parties),
+		}
+		net.rounds[ep.round] = round
+		if net.Timeout > 0 {
+			time.AfterFunc(net.Timeout, func() {
+				net.mutex.Lock()
+				defer net.mutex.Unlock()
+				round.closed = true
+				net.cond.Broadcast()
+			})
+		}
+	}
+	if round.closed {
+		ep.round++
+		return nil, fmt.Errorf("round %d finished before party %d sent its message", ep.round-1, ep.id)
+	}
+	round.msgs[ep.id] = msg
+	round.received++
+	if round.received == net.parties {
+		round.closed = true
+		net.cond.Broadcast()
+	}
+	for !round.closed {
+		net.cond.Wait()
+	}
+	msgs := append([]interface{}{}, round.msgs...)
+	round.read++
+	if round.read == net.parties {
+		delete(net.rounds, ep.round)
+	}
+	ep.round++
+	return msgs, nil
+}
@@ -0,0 +1,251 @@
+package mpc
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+// Party represents A key share holder that runs protocols with the other holders over
+// A network. All the parties of A protocol must call the same methods in the same order.
+type Party struct {
+	Share *tcpaillier.KeyShare
+	Net   Network
+}
+
+// bitsCommit is the message of A party with its encrypted random bits.
+type bitsCommit struct {
+	Index  uint8
+	Bits   []*big.Int
+	Proofs []*tcpaillier.BitZK
+}
+
+// openShare is the message of A party with its decryption shares of A list of values.
+type openShare struct {
+	Index  uint8
+	Shares []*tcpaillier.DecryptionShare
+	Proofs []*tcpaillier.DecryptShareZK
+}
+
+// Mul returns the encryption of a*b from the encryptions of a and b.
+func (p *Party) Mul(ca, cb *big.Int) (*big.Int, error) {
+	cabs, err := p.MulMany([]*big.Int{ca}, []*big.Int{cb})
+	if err != nil {
+		return nil, err
+	}
+	return cabs[0], nil
+}
+
+// MulMany returns the encryptions of as[i]*bs[i] for every i, running all the multiplications
+// in the same rounds.
+func (p *Party) MulMany(as, bs []*big.Int) (cabs []*big.Int, err error) {
+	if len(as) != len(bs) {
+		err = fmt.Errorf("there are %d first factors but %d second ones", len(as), len(bs))
+		return
+	}
+	muls := make([]*Mul, len(as))
+	commits := make([]*MulCommit, len(as))
+	for i := range muls {
+		muls[i] = NewMul(p.Share, as[i], bs[i])
+		commits[i], err = muls[i].Commit()
+		if err != nil {
+			return
+		}
+	}
+	msgs, err := p.Net.Exchange(commits)
+	if err != nil {
+		return
+	}
+	decs := make([]*MulDecrypt, len(as))
+	for i, mul := range muls {
+		received := make([]*MulCommit, 0, len(msgs))
+		for _, msg := range msgs {
+			if partyCommits, ok := msg.([]*MulCommit); ok && len(partyCommits) == len(as) {
+				received = append(received, partyCommits[i])
+			}
+		}
+		decs[i], err = mul.Decrypt(received)
+		if err != nil {
+			return
+		}
+	}
+	msgs, err = p.Net.Exchange(decs)
+	if err != nil {
+		return
+	}
+	cabs = make([]*big.Int, len(as))
+	for i, mul := range muls {
+		received := make([]*MulDecrypt, 0, len(msgs))
+		for _, msg := range msgs {
+			if partyDecs, ok := msg.([]*MulDecrypt); ok && len(partyDecs) == len(as) {
+				received = append(received, partyDecs[i])
+			}
+		}
+		cabs[i], err = mul.Finish(received)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Open decrypts jointly A list of encrypted values, and returns them to every party.
+func (p *Party) Open(cs ...*big.Int) (decs []*big.Int, err error) {
+	pk := p.Share.PubKey
+	msg := &openShare{
+		Index:  p.Share.Index,
+		Shares: make([]*tcpaillier.DecryptionShare, len(cs)),
+		Proofs: make([]*tcpaillier.DecryptShareZK, len(cs)),
+	}
+	for i, c := range cs {
+		msg.Shares[i], msg.Proofs[i], err = p.Share.PartialDecryptWithProof(c)
+		if err != nil {
+			return
+		}
+	}
+	msgs, err := p.Net.Exchange(msg)
+	if err != nil {
+		return
+	}
+	decs = make([]*big.Int, len(cs))
+	for i, c := range cs {
+		var shares []*tcpaillier.DecryptionShare
+		for _, m := range msgs {
+			partyShares, ok := m.(*openShare)
+			if !ok || len(partyShares.Shares) != len(cs) || len(partyShares.Proofs) != len(cs) {
+				continue
+			}
+			share, proof := partyShares.Shares[i], partyShares.Proofs[i]
+			if share == nil || proof == nil || share.Index != partyShares.Index {
+				continue
+			}
+			if proof.Verify(pk, c, share) != nil {
+				continue
+			}
+			shares = append(shares, share)
+		}
+		decs[i], err = pk.CombineShares(shares...)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// RandomBits returns n encrypted bits that are random and unknown to every party, as long
+// as one of the parties is honest. Each bit is the exclusive or of one random bit of each
+// party, sent with A proof that it is 0 or 1.
+func (p *Party) RandomBits(n int) (bits []*big.Int, err error) {
+	contributions, err := p.commitBits(n)
+	if err != nil {
+		return
+	}
+	// The received messages are shared with the network, so they are not modified.
+	bits = append([]*big.Int{}, contributions[0]...)
+	for _, other := range contributions[1:] {
+		// x xor y = x + y - 2xy
+		var xys []*big.Int
+		xys, err = p.MulMany(bits, other)
+		if err != nil {
+			return
+		}
+		for i := range bits {
+			bits[i], err = p.linear(nil, bits[i], one, other[i], one, xys[i], big.NewInt(-2))
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// commitBits sends n encrypted random bits of this party to the others, and returns the
+// bits of every party with valid proofs, sorted by the party index.
+func (p *Party) commitBits(n int) (contributions [][]*big.Int, err error) {
+	pk := p.Share.PubKey
+	msg := &bitsCommit{
+		Index:  p.Share.Index,
+		Bits:   make([]*big.Int, n),
+		Proofs: make([]*tcpaillier.BitZK, n),
+	}
+	for i := range msg.Bits {
+		var bit *big.Int
+		bit, err = rand.Int(rand.Reader, two)
+		if err != nil {
+			return
+		}
+		msg.Bits[i], msg.Proofs[i], err = pk.EncryptBitWithProof(bit)
+		if err != nil {
+			return
+		}
+	}
+	msgs, err := p.Net.Exchange(msg)
+	if err != nil {
+		return
+	}
+	var valid []*bitsCommit
+	seen := make(map[uint8]struct{})
+	for _, m := range msgs {
+		commit, ok := m.(*bitsCommit)
+		if !ok || len(commit.Bits) != n || len(commit.Proofs) != n {
+			continue
+		}
+		if _, ok := seen[commit.Index]; ok {
+			continue
+		}
+		validProofs := true
+		for i, bit := range commit.Bits {
+			if bit == nil || commit.Proofs[i] == nil || commit.Proofs[i].Verify(pk, bit) != nil {
+				validProofs = false
+				break
+			}
+		}
+		if validProofs {
+			seen[commit.Index] = struct{}{}
+			valid = append(valid, commit)
+		}
+	}
+	if _, ok := seen[p.Share.Index]; !ok {
+		err = fmt.Errorf("random bits of this party are missing or invalid")
+		return
+	}
+	sort.Slice(valid, func(i, j int) bool {
+		return valid[i].Index < valid[j].Index
+	})
+	for _, commit := range valid {
+		contributions = append(contributions, commit.Bits)
+	}
+	return
+}
+
+// linear returns the encryption of k + sum(alpha_i * x_i), where k is A public constant,
+// that can be nil, and the rest of the arguments are pairs of encrypted values x_i and
+// public constants alpha_i.
+func (p *Party) linear(k *big.Int, terms ...*big.Int) (c *big.Int, err error) {
+	pk := p.Share.PubKey
+	nToS := pk.Cache().NToS
+	if k == nil {
+		k = new(big.Int)
+	}
+	// The encryption of a constant does not need to be random.
+	c, err = pk.EncryptFixed(new(big.Int).Mod(k, nToS), one)
+	if err != nil {
+		return
+	}
+	for i := 0; i+1 < len(terms); i += 2 {
+		alpha := new(big.Int).Mod(terms[i+1], nToS)
+		var term *big.Int
+		term, err = pk.MultiplyFixed(terms[i], alpha, one)
+		if err != nil {
+			return
+		}
+		c, err = pk.Add(c, term)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
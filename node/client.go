@@ -0,0 +1,154 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/recipient"
+)
+
+// maxResponseSize is the maximum size in bytes of the body of A response.
+const maxResponseSize = 1 << 20
+
+// Client requests partial decryptions to nodes, authenticating itself with A token.
+// The endpoints are the base URLs of the nodes. It complies with coordinator.Transport
+// interface.
+type Client struct {
+	HTTP  *http.Client
+	Token string
+}
+
+// NewClient returns A client that uses the token provided and the default HTTP client.
+func NewClient(token string) *Client {
+	return &Client{
+		HTTP:  http.DefaultClient,
+		Token: token,
+	}
+}
+
+// RequestShare requests the partial decryption of c to the node on endpoint, and returns
+// the share and its proof. It does not verify the proof.
+func (cl *Client) RequestShare(ctx context.Context, endpoint string, c *big.Int) (ds *tcpaillier.DecryptionShare, zk *tcpaillier.DecryptShareZK, err error) {
+	decResp, err := cl.request(ctx, endpoint, &DecryptRequest{C: c})
+	if err != nil {
+		return
+	}
+	if decResp.Share == nil || decResp.Proof == nil {
+		err = fmt.Errorf("node %s did not send its share and proof", endpoint)
+		return
+	}
+	ds, zk = decResp.Share, decResp.Proof
+	return
+}
+
+// RequestLabeledShare requests the partial decryption of A labeled encrypted value to the
+// node on endpoint, and returns the share and its proof. The node decrypts it only if its
+// proof is valid. It does not verify the proof of the share.
+func (cl *Client) RequestLabeledShare(ctx context.Context, endpoint string, lc *tcpaillier.LabeledCiphertext) (ds *tcpaillier.DecryptionShare, zk *tcpaillier.DecryptShareZK, err error) {
+	decResp, err := cl.request(ctx, endpoint, &DecryptRequest{Labeled: lc})
+	if err != nil {
+		return
+	}
+	if decResp.Share == nil || decResp.Proof == nil {
+		err = fmt.Errorf("node %s did not send its share and proof", endpoint)
+		return
+	}
+	ds, zk = decResp.Share, decResp.Proof
+	return
+}
+
+// RequestSealedShare requests the partial decryption of c to the node on endpoint, sealed
+// to the recipient key. The share can be opened only with the private key of the recipient.
+func (cl *Client) RequestSealedShare(ctx context.Context, endpoint string, c *big.Int, to *recipient.PublicKey) (sealed *recipient.SealedShare, err error) {
+	decResp, err := cl.request(ctx, endpoint, &DecryptRequest{C: c, Recipient: to})
+	if err != nil {
+		return
+	}
+	if decResp.Sealed == nil {
+		err = fmt.Errorf("node %s did not send its sealed share", endpoint)
+		return
+	}
+	sealed = decResp.Sealed
+	return
+}
+
+// request sends A partial decryption request to the node on endpoint, and returns its answer.
+func (cl *Client) request(ctx context.Context, endpoint string, decReq *DecryptRequest) (decResp *DecryptResponse, err error) {
+	body, err := json.Marshal(decReq)
+	if err != nil {
+		return
+	}
+	url := strings.TrimSuffix(endpoint, "/") + DecryptPath
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cl.Token)
+	httpClient := cl.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize))
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		if decoder.Decode(&errResp) != nil || errResp.Error == "" {
+			errResp.Error = http.StatusText(resp.StatusCode)
+		}
+		err = fmt.Errorf("node %s answered with status %d: %s", endpoint, resp.StatusCode, errResp.Error)
+		return
+	}
+	decResp = &DecryptResponse{}
+	if err = decoder.Decode(decResp); err != nil {
+		err = fmt.Errorf("cannot decode answer of node %s: %v", endpoint, err)
+		decResp = nil
+	}
+	return
+}
+
+// Loopback is A round tripper that sends the requests to handlers on the same process,
+// chosen by the host of the request URL. It allows to run A committee of nodes without
+// network, with endpoints like "http://node-1".
+type Loopback map[string]http.Handler
+
+// RoundTrip answers the request with the handler of its host.
+func (lb Loopback) RoundTrip(req *http.Request) (*http.Response, error) {
+	handler, ok := lb[req.URL.Host]
+	if !ok {
+		return nil, fmt.Errorf("unknown loopback host %s", req.URL.Host)
+	}
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	if resp.Body == nil {
+		resp.Body = ioutil.NopCloser(bytes.NewReader(nil))
+	}
+	return resp, nil
+}
+
+// Client returns A client that sends its requests to the handlers of the loopback.
+func (lb Loopback) Client(token string) *Client {
+	return &Client{
+		HTTP:  &http.Client{Transport: lb},
+		Token: token,
+	}
+}
@@ -0,0 +1,215 @@
+package node_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/coordinator"
+	"github.com/niclabs/tcpaillier/node"
+	"github.com/niclabs/tcpaillier/recipient"
+)
+
+const k = 3
+const l = 5
+const s = 1
+
+const bitSize = 256
+
+const token = "coordinator-token"
+
+// newCommittee returns A loopback with A node for each key share, and their endpoints.
+func newCommittee(t *testing.T, shares []*tcpaillier.KeyShare, policies ...*node.ClientPolicy) (node.Loopback, []string) {
+	lb := make(node.Loopback)
+	endpoints := make([]string, len(shares))
+	for i, share := range shares {
+		srv, err := node.NewServer(share, policies...)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		host := fmt.Sprintf("node-%d", share.Index)
+		lb[host] = srv
+		endpoints[i] = "http://" + host
+	}
+	return lb, endpoints
+}
+
+func TestServer_committee(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	lb, endpoints := newCommittee(t, shares, &node.ClientPolicy{Name: "coordinator", Token: token})
+	c, _, err := pk.Encrypt(big.NewInt(1234))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	co := coordinator.New(pk, lb.Client(token), endpoints...)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	dec, report, err := co.Decrypt(ctx, c)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if dec.Cmp(big.NewInt(1234)) != 0 {
+		t.Errorf("decrypted value should be 1234, but it is %s", dec)
+	}
+	if faulty := report.Faulty(); len(faulty) != 0 {
+		t.Errorf("there should not be faulty nodes, but there are %v", faulty)
+	}
+}
+
+func TestNewServer_invalidPolicies(t *testing.T) {
+	shares, _, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := node.NewServer(shares[0], nil); err == nil {
+		t.Errorf("server with an undefined policy should not be created")
+	}
+	first := &node.ClientPolicy{Name: "coordinator", Token: token}
+	other := &node.ClientPolicy{Name: "other", Token: token, Rate: 0.01, Burst: 1}
+	if _, err := node.NewServer(shares[0], first, other); err == nil {
+		t.Errorf("server with two clients with the same token should not be created")
+	}
+}
+
+func TestServer_unauthorized(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	lb, endpoints := newCommittee(t, shares[:1], &node.ClientPolicy{Name: "coordinator", Token: token})
+	c, _, err := pk.Encrypt(big.NewInt(1))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, _, err = lb.Client("wrong-token").RequestShare(context.Background(), endpoints[0], c)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("request with a wrong token should be unauthorized, but the error is %v", err)
+	}
+}
+
+func TestServer_rateLimit(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	lb, endpoints := newCommittee(t, shares[:1], &node.ClientPolicy{
+		Name:  "coordinator",
+		Token: token,
+		Rate:  0.01,
+		Burst: 2,
+	})
+	c, _, err := pk.Encrypt(big.NewInt(1))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	client := lb.Client(token)
+	for i := 0; i < 2; i++ {
+		ds, zk, err := client.RequestShare(context.Background(), endpoints[0], c)
+		if err != nil {
+			t.Fatalf("request %d should be answered: %v", i+1, err)
+		}
+		if err := zk.Verify(pk, c, ds); err != nil {
+			t.Errorf("share of request %d should be valid: %v", i+1, err)
+		}
+	}
+	_, _, err = client.RequestShare(context.Background(), endpoints[0], c)
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("request over the burst should be rate limited, but the error is %v", err)
+	}
+}
+
+func TestServer_sealed(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	priv, err := recipient.GenerateKey()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	policy := &node.ClientPolicy{Name: "coordinator", Token: token, SealedOnly: true}
+	if _, err := node.NewServer(shares[0], policy); err == nil {
+		t.Errorf("server with A sealed only client without recipients should not be created")
+	}
+	policy.Recipients = []recipient.PublicKey{priv.PublicKey}
+	lb, endpoints := newCommittee(t, shares, policy)
+	c, _, err := pk.Encrypt(big.NewInt(1234))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	client := lb.Client(token)
+	_, _, err = client.RequestShare(context.Background(), endpoints[0], c)
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("request without recipient should be forbidden, but the error is %v", err)
+	}
+	// The client cannot seal the shares to its own key.
+	own, err := recipient.GenerateKey()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, err = client.RequestSealedShare(context.Background(), endpoints[0], c, &own.PublicKey)
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("request with A recipient not allowed should be forbidden, but the error is %v", err)
+	}
+	sealed := make([]*recipient.SealedShare, len(endpoints))
+	for i, endpoint := range endpoints {
+		if sealed[i], err = client.RequestSealedShare(context.Background(), endpoint, c, &priv.PublicKey); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	dec, err := priv.Combine(pk, c, sealed...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if dec.Cmp(big.NewInt(1234)) != 0 {
+		t.Errorf("decrypted value should be 1234, but it is %s", dec)
+	}
+}
+
+func TestServer_labeled(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	lb, endpoints := newCommittee(t, shares, &node.ClientPolicy{Name: "coordinator", Token: token, LabeledOnly: true})
+	lc, err := pk.EncryptLabeled(big.NewInt(1234), []byte("ballot 7"))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	client := lb.Client(token)
+	// The value stripped of its label is not decrypted.
+	_, _, err = client.RequestShare(context.Background(), endpoints[0], lc.C)
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("request without label should be forbidden, but the error is %v", err)
+	}
+	relabeled := *lc
+	relabeled.Label = []byte("ballot 8")
+	if _, _, err := client.RequestLabeledShare(context.Background(), endpoints[0], &relabeled); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("request with another label should be rejected, but the error is %v", err)
+	}
+	dss := make([]*tcpaillier.DecryptionShare, len(endpoints))
+	for i, endpoint := range endpoints {
+		ds, zk, err := client.RequestLabeledShare(context.Background(), endpoint, lc)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if err := zk.Verify(pk, lc.C, ds); err != nil {
+			t.Errorf("share of node %d should be valid: %v", ds.Index, err)
+		}
+		dss[i] = ds
+	}
+	dec, err := pk.CombineShares(dss...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if dec.Cmp(big.NewInt(1234)) != 0 {
+		t.Errorf("decrypted value should be 1234, but it is %s", dec)
+	}
+}
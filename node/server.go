@@ -0,0 +1,271 @@
+// Package node implements A server that holds A key share and answers partial decryption
+// requests over HTTP, and A client for it that can be used as A coordinator transport.
+package node
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/recipient"
+)
+
+// DecryptPath is the path where A node answers partial decryption requests.
+const DecryptPath = "/partial-decrypt"
+
+// maxRequestSize is the maximum size in bytes of the body of A request.
+const maxRequestSize = 1 << 20
+
+// DecryptRequest is the body of A partial decryption request. It has either an encrypted
+// value C or A labeled encrypted value, that is decrypted only if its proof is valid. If
+// Recipient is defined, the share and its proof are sealed to it.
+type DecryptRequest struct {
+	C         *big.Int                      `json:",omitempty"`
+	Labeled   *tcpaillier.LabeledCiphertext `json:",omitempty"`
+	Recipient *recipient.PublicKey          `json:",omitempty"`
+}
+
+// DecryptResponse is the body of the answer to A partial decryption request. It has the
+// share and its proof, or the sealed share if the request had A recipient.
+type DecryptResponse struct {
+	Share  *tcpaillier.DecryptionShare `json:",omitempty"`
+	Proof  *tcpaillier.DecryptShareZK  `json:",omitempty"`
+	Sealed *recipient.SealedShare      `json:",omitempty"`
+}
+
+// errorResponse is the body of the answer to A request that failed.
+type errorResponse struct {
+	Error string
+}
+
+// ClientPolicy represents A client allowed to request partial decryptions to A node.
+// The client authenticates itself with its token as A bearer token. Rate is the number
+// of requests per second it can send, and Burst is the number of requests it can send
+// at once. If Rate is zero, the client is not rate limited. If SealedOnly is true, the
+// client must send one of its Recipients keys on its requests, so its shares are never
+// sent in clear and only the holders of those keys can open them. If Recipients is not
+// empty, the shares are sealed only to those keys. If LabeledOnly is true, the client
+// must send labeled encrypted values, so the node cannot be used to decrypt values
+// stripped of their labels.
+type ClientPolicy struct {
+	Name        string
+	Token       string
+	Rate        float64
+	Burst       int
+	SealedOnly  bool
+	Recipients  []recipient.PublicKey
+	LabeledOnly bool
+}
+
+// Server answers the partial decryption requests of the clients allowed by its policies,
+// using its key share signer. It complies with http.Handler interface.
+type Server struct {
+	signer  tcpaillier.ShareSigner
+	clients []*client
+}
+
+// client is the state of A client of A server.
+type client struct {
+	policy *ClientPolicy
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewServer returns A server for the key share signer, that answers to the clients of the
+// policies provided. A *tcpaillier.KeyShare can be used as signer.
+func NewServer(signer tcpaillier.ShareSigner, policies ...*ClientPolicy) (srv *Server, err error) {
+	if signer == nil || signer.PublicKey() == nil {
+		err = fmt.Errorf("key share signer is not defined")
+		return
+	}
+	srv = &Server{
+		signer:  signer,
+		clients: make([]*client, len(policies)),
+	}
+	tokens := make(map[string]bool)
+	for i, policy := range policies {
+		if policy == nil {
+			err = fmt.Errorf("client policy %d is not defined", i)
+			return
+		}
+		if policy.Token == "" {
+			err = fmt.Errorf("client %q has an empty token", policy.Name)
+			return
+		}
+		if tokens[policy.Token] {
+			err = fmt.Errorf("client %q has the token of another client", policy.Name)
+			return
+		}
+		tokens[policy.Token] = true
+		if policy.Rate < 0 || policy.Burst < 0 {
+			err = fmt.Errorf("client %q has a negative rate limit", policy.Name)
+			return
+		}
+		if policy.SealedOnly && len(policy.Recipients) == 0 {
+			err = fmt.Errorf("client %q can only request sealed shares, but it has no recipients", policy.Name)
+			return
+		}
+		srv.clients[i] = &client{
+			policy: policy,
+			tokens: float64(policy.burst()),
+			last:   time.Now(),
+		}
+	}
+	signer.PublicKey().Cache()
+	return
+}
+
+// ServeHTTP answers A partial decryption request.
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != DecryptPath {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown path %s", r.URL.Path))
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	cl := srv.authorize(r)
+	if cl == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("client not authorized"))
+		return
+	}
+	if wait := cl.allow(time.Now()); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeError(w, http.StatusTooManyRequests, fmt.Errorf("rate limit of client %q exceeded", cl.policy.Name))
+		return
+	}
+	var req DecryptRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("cannot decode request: %v", err))
+		return
+	}
+	c := req.C
+	if req.Labeled != nil {
+		if req.C != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("request should have an encrypted value or A labeled one, but not both"))
+			return
+		}
+		if err := req.Labeled.Verify(srv.signer.PublicKey()); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("refusing to decrypt: %v", err))
+			return
+		}
+		c = req.Labeled.C
+	} else if cl.policy.LabeledOnly {
+		writeError(w, http.StatusForbidden, fmt.Errorf("client %q must request labeled encrypted values", cl.policy.Name))
+		return
+	}
+	if c == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("encrypted value is not defined"))
+		return
+	}
+	if req.Recipient != nil {
+		if !cl.policy.allowRecipient(req.Recipient) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("client %q cannot seal shares to the recipient requested", cl.policy.Name))
+			return
+		}
+		sealed, err := recipient.SealWith(srv.signer, c, req.Recipient)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, &DecryptResponse{Sealed: sealed})
+		return
+	}
+	if cl.policy.SealedOnly {
+		writeError(w, http.StatusForbidden, fmt.Errorf("client %q must request sealed shares", cl.policy.Name))
+		return
+	}
+	share, err := srv.signer.PartialDecrypt(c)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	proof, err := srv.signer.PartialDecryptProof(c, share)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &DecryptResponse{
+		Share: share,
+		Proof: proof,
+	})
+}
+
+// authorize returns the client with the bearer token of the request, or nil if there is
+// not one. Every token is compared in constant time.
+func (srv *Server) authorize(r *http.Request) *client {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil
+	}
+	token := []byte(strings.TrimPrefix(auth, "Bearer "))
+	var found *client
+	for _, cl := range srv.clients {
+		if subtle.ConstantTimeCompare(token, []byte(cl.policy.Token)) == 1 {
+			found = cl
+		}
+	}
+	return found
+}
+
+// allow takes A token of the bucket of the client. If there are no tokens, it returns
+// the time to wait until the next one.
+func (cl *client) allow(now time.Time) time.Duration {
+	rate := cl.policy.Rate
+	if rate == 0 {
+		return 0
+	}
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	cl.tokens = math.Min(cl.tokens+now.Sub(cl.last).Seconds()*rate, float64(cl.policy.burst()))
+	cl.last = now
+	if cl.tokens < 1 {
+		return time.Duration((1 - cl.tokens) / rate * float64(time.Second))
+	}
+	cl.tokens--
+	return 0
+}
+
+// allowRecipient returns true if the shares can be sealed to the recipient key. Any key is
+// allowed if the policy has no recipients.
+func (policy *ClientPolicy) allowRecipient(to *recipient.PublicKey) bool {
+	if len(policy.Recipients) == 0 {
+		return true
+	}
+	for _, allowed := range policy.Recipients {
+		if allowed == *to {
+			return true
+		}
+	}
+	return false
+}
+
+// burst returns the size of the token bucket of the client, that is at least 1.
+func (policy *ClientPolicy) burst() int {
+	if policy.Burst < 1 {
+		return 1
+	}
+	return policy.Burst
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, &errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,335 @@
+// Package pir implements the single server private information retrieval scheme of Lipmaa,
+// based on the Damgård-Jurik cryptosystem. The items of the database are arranged on A
+// hypercube with Depth dimensions of Side items each. A query has an encrypted selection
+// vector for each dimension, and the server uses the first one to select A row of every
+// column, the second one to select one of those rows, and so on. The output of each level is
+// A list of encrypted values, that are encrypted again by the next level, using A greater S
+// parameter, so the response has A single value encrypted Depth times.
+//
+// Every level uses the same threshold key with A different S parameter. The key must be
+// created with the S parameter of the last level, and the keys of the other levels are
+// derived from it with LevelKey and LevelShare. The committee decrypts the response once
+// for each level, from the last one to the first one, and the result of the last decryption
+// is the item requested.
+package pir
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+var one = big.NewInt(1)
+
+// maxInt is the greatest value of an int.
+const maxInt = int(^uint(0) >> 1)
+
+// Params describes the arrangement of A database. Side is the number of items of each
+// dimension, Depth is the number of dimensions, and S is the parameter of the first level.
+// The items must be smaller than N^S, and the key must have A parameter of S+Depth-1.
+type Params struct {
+	S     uint8
+	Side  int
+	Depth int
+}
+
+// Query is A request for an item of A database. Levels has the encrypted selection vector
+// of each dimension, and the vector of level j is encrypted with A parameter of S+j.
+type Query struct {
+	Params
+	Fingerprint tcpaillier.Fingerprint
+	Levels      [][]*big.Int
+}
+
+// Response is the answer to A query. C is the item requested, encrypted Depth times.
+type Response struct {
+	Params
+	C *big.Int
+}
+
+// NewParams returns the parameters of A database of the size provided, arranged on depth
+// dimensions, for the public key. The S parameter of the first level is the greatest one
+// allowed by the key.
+func NewParams(pk *tcpaillier.PubKey, size, depth int) (params *Params, err error) {
+	if size < 1 {
+		err = fmt.Errorf("database should have at least one item")
+		return
+	}
+	if depth < 1 || depth > int(pk.S) {
+		err = fmt.Errorf("depth should be between 1 and %d, but it is %d", pk.S, depth)
+		return
+	}
+	side := 1
+	for capacity(side, depth) < size {
+		side++
+	}
+	params = &Params{
+		S:     pk.S - uint8(depth) + 1,
+		Side:  side,
+		Depth: depth,
+	}
+	return
+}
+
+// Capacity returns the number of items of A database with these parameters.
+func (params *Params) Capacity() int {
+	return capacity(params.Side, params.Depth)
+}
+
+// validate checks that the parameters can be used with the public key.
+func (params *Params) validate(pk *tcpaillier.PubKey) error {
+	if params.S < 1 || params.Side < 1 || params.Depth < 1 {
+		return fmt.Errorf("S, side and depth should be positive")
+	}
+	if int(params.S)+params.Depth-1 != int(pk.S) {
+		return fmt.Errorf("public key should have S = %d, but it has S = %d", int(params.S)+params.Depth-1, pk.S)
+	}
+	return nil
+}
+
+// LevelKey returns the public key with the S parameter provided, that must not be greater
+// than the one of pk.
+func LevelKey(pk *tcpaillier.PubKey, s uint8) (levelPK *tcpaillier.PubKey, err error) {
+	if s < 1 || s > pk.S {
+		err = fmt.Errorf("S should be between 1 and %d, but it is %d", pk.S, s)
+		return
+	}
+	if s == pk.S {
+		return pk, nil
+	}
+	nToS := new(big.Int).Exp(pk.N, big.NewInt(int64(s)), nil)
+	nToSPlusOne := new(big.Int).Mul(nToS, pk.N)
+	levelPK = &tcpaillier.PubKey{
+		N:        pk.N,
+		V:        new(big.Int).Mod(pk.V, nToSPlusOne),
+		Vi:       make([]*big.Int, len(pk.Vi)),
+		L:        pk.L,
+		K:        pk.K,
+		S:        s,
+		Delta:    pk.Delta,
+		Constant: new(big.Int).Mod(pk.Constant, nToS),
+	}
+	for i, vi := range pk.Vi {
+		levelPK.Vi[i] = new(big.Int).Mod(vi, nToSPlusOne)
+	}
+	if err = levelPK.Validate(); err != nil {
+		levelPK = nil
+	}
+	return
+}
+
+// LevelShare returns A copy of the key share for the public key with the S parameter
+// provided. The copy should be destroyed after using it.
+func LevelShare(share *tcpaillier.KeyShare, s uint8) (levelShare *tcpaillier.KeyShare, err error) {
+	if share.Si == nil {
+		err = tcpaillier.ErrKeyShareDestroyed
+		return
+	}
+	levelPK, err := LevelKey(share.PubKey, s)
+	if err != nil {
+		return
+	}
+	levelShare = &tcpaillier.KeyShare{
+		PubKey: levelPK,
+		Index:  share.Index,
+		Si:     new(big.Int).Set(share.Si),
+	}
+	return
+}
+
+// NewQuery returns A query for the item with the index provided.
+func NewQuery(pk *tcpaillier.PubKey, params *Params, index int) (q *Query, err error) {
+	if err = params.validate(pk); err != nil {
+		return
+	}
+	if index < 0 || index >= params.Capacity() {
+		err = fmt.Errorf("index should be between 0 and %d, but it is %d", params.Capacity()-1, index)
+		return
+	}
+	q = &Query{
+		Params:      *params,
+		Fingerprint: pk.Fingerprint(),
+		Levels:      make([][]*big.Int, params.Depth),
+	}
+	for j := range q.Levels {
+		levelPK, err := LevelKey(pk, params.S+uint8(j))
+		if err != nil {
+			return nil, err
+		}
+		digit := index % params.Side
+		index /= params.Side
+		q.Levels[j] = make([]*big.Int, params.Side)
+		for k := range q.Levels[j] {
+			bit := new(big.Int)
+			if k == digit {
+				bit.SetInt64(1)
+			}
+			if q.Levels[j][k], _, err = levelPK.Encrypt(bit); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return
+}
+
+// Answer computes the response to A query on the database. The item with index i of the
+// database has the coordinate (i mod Side) on the first dimension, (i / Side mod Side) on the
+// second one, and so on. The missing items are zero. The query must have the parameters
+// returned by NewParams for the size of the database and the depth of the query.
+func Answer(pk *tcpaillier.PubKey, db []*big.Int, q *Query) (resp *Response, err error) {
+	if q == nil {
+		err = fmt.Errorf("query is not defined")
+		return
+	}
+	if len(db) == 0 {
+		err = fmt.Errorf("database is empty")
+		return
+	}
+	// The parameters are computed again from the database, so the client cannot make the
+	// server allocate and fold more values than the ones of the database.
+	expected, err := NewParams(pk, len(db), q.Depth)
+	if err != nil {
+		return
+	}
+	if q.Params != *expected {
+		err = fmt.Errorf("query should have S = %d and side %d for %d items on %d dimensions, but it has S = %d and side %d", expected.S, expected.Side, len(db), expected.Depth, q.S, q.Side)
+		return
+	}
+	if err = q.validate(pk); err != nil {
+		return
+	}
+	firstPK, err := LevelKey(pk, q.S)
+	if err != nil {
+		return
+	}
+	for i, item := range db {
+		if item != nil && (item.Sign() < 0 || item.Cmp(firstPK.Cache().NToS) >= 0) {
+			err = fmt.Errorf("item %d should be between 0 and N^%d", i, q.S)
+			return
+		}
+	}
+	values := db
+	for j, sel := range q.Levels {
+		levelPK, err := LevelKey(pk, q.S+uint8(j))
+		if err != nil {
+			return nil, err
+		}
+		// The rows of the missing items are encryptions of zero, so the next level has A
+		// valid ciphertext in every position.
+		next := make([]*big.Int, capacity(q.Side, q.Depth-j-1))
+		for r := range next {
+			row := values[min(len(values), r*q.Side):min(len(values), (r+1)*q.Side)]
+			if next[r], err = selectRow(levelPK, sel, row); err != nil {
+				return nil, err
+			}
+		}
+		values = next
+	}
+	resp = &Response{
+		Params: q.Params,
+		C:      values[0],
+	}
+	return
+}
+
+// Decrypt decrypts A response, calling decrypt once for each level, from the last one to the
+// first one. decrypt must return the decryption of c with the public key provided, usually
+// by asking the committee for its decryption shares with the keys returned by LevelShare.
+func Decrypt(pk *tcpaillier.PubKey, resp *Response, decrypt func(levelPK *tcpaillier.PubKey, c *big.Int) (*big.Int, error)) (item *big.Int, err error) {
+	if err = resp.validate(pk); err != nil {
+		return
+	}
+	if err = pk.ValidateCiphertext(resp.C); err != nil {
+		return
+	}
+	item = resp.C
+	for j := resp.Depth - 1; j >= 0; j-- {
+		levelPK, err := LevelKey(pk, resp.S+uint8(j))
+		if err != nil {
+			return nil, err
+		}
+		if item, err = decrypt(levelPK, item); err != nil {
+			return nil, fmt.Errorf("cannot decrypt level %d: %w", j+1, err)
+		}
+	}
+	return
+}
+
+// validate checks that the query is for the public key, and that its selection vectors are
+// valid encrypted values.
+func (q *Query) validate(pk *tcpaillier.PubKey) error {
+	if q.Fingerprint != pk.Fingerprint() {
+		return fmt.Errorf("query: %w", tcpaillier.ErrFingerprintMismatch)
+	}
+	if err := q.Params.validate(pk); err != nil {
+		return err
+	}
+	if len(q.Levels) != q.Depth {
+		return fmt.Errorf("query should have %d levels, but it has %d", q.Depth, len(q.Levels))
+	}
+	for j, sel := range q.Levels {
+		if len(sel) != q.Side {
+			return fmt.Errorf("level %d should have %d values, but it has %d", j+1, q.Side, len(sel))
+		}
+		levelPK, err := LevelKey(pk, q.S+uint8(j))
+		if err != nil {
+			return err
+		}
+		for k, c := range sel {
+			if err := levelPK.ValidateCiphertext(c); err != nil {
+				return fmt.Errorf("value %d of level %d: %w", k, j+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// selectRow returns the encryption of the value of the row selected by sel, rerandomized.
+// The nil and zero values are skipped.
+func selectRow(pk *tcpaillier.PubKey, sel, row []*big.Int) (c *big.Int, err error) {
+	terms := make([]*big.Int, 0, len(row))
+	for k, x := range row {
+		if x == nil || x.Sign() == 0 {
+			continue
+		}
+		term, err := pk.MultiplyFixed(sel[k], x, one)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		c, _, err = pk.Encrypt(new(big.Int))
+		return
+	}
+	sum, err := pk.Add(terms...)
+	if err != nil {
+		return
+	}
+	r, err := pk.RandomModNToSPlusOneStar()
+	if err != nil {
+		return
+	}
+	return pk.ReRand(sum, r)
+}
+
+// capacity returns side^depth.
+// capacity returns side^depth, or maxInt if it does not fit on an int.
+func capacity(side, depth int) int {
+	c := 1
+	for i := 0; i < depth; i++ {
+		if side > 0 && c > maxInt/side {
+			return maxInt
+		}
+		c *= side
+	}
+	return c
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,139 @@
+package pir_test
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/pir"
+)
+
+const k = 2
+const l = 3
+
+const bitSize = 256
+
+// committee returns A decryption function that uses the first K key shares at the level
+// of the public key provided, and verifies their proofs.
+func committee(t *testing.T, shares []*tcpaillier.KeyShare) func(*tcpaillier.PubKey, *big.Int) (*big.Int, error) {
+	return func(levelPK *tcpaillier.PubKey, c *big.Int) (*big.Int, error) {
+		dss := make([]*tcpaillier.DecryptionShare, k)
+		for i, share := range shares[:k] {
+			levelShare, err := pir.LevelShare(share, levelPK.S)
+			if err != nil {
+				return nil, err
+			}
+			ds, zk, err := levelShare.PartialDecryptWithProof(c)
+			levelShare.Destroy()
+			if err != nil {
+				return nil, err
+			}
+			if err := zk.Verify(levelPK, c, ds); err != nil {
+				t.Errorf("decryption share proof at level S=%d should be valid: %v", levelPK.S, err)
+			}
+			dss[i] = ds
+		}
+		return levelPK.CombineShares(dss...)
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	for _, depth := range []int{1, 2, 3} {
+		shares, pk, err := tcpaillier.NewKey(bitSize, uint8(depth), l, k)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		params, err := pir.NewParams(pk, 7, depth)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if params.Capacity() < 7 {
+			t.Fatalf("capacity should be at least 7, but it is %d", params.Capacity())
+		}
+		// The last item is missing, so it is zero.
+		db := make([]*big.Int, 7)
+		for i := range db[:6] {
+			if db[i], err = rand.Int(rand.Reader, pk.N); err != nil {
+				t.Fatalf("%v", err)
+			}
+		}
+		db[2] = new(big.Int)
+		for _, index := range []int{0, 2, 5, 6} {
+			q, err := pir.NewQuery(pk, params, index)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			resp, err := pir.Answer(pk, db, q)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			item, err := pir.Decrypt(pk, resp, committee(t, shares))
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			expected := new(big.Int)
+			if db[index] != nil {
+				expected = db[index]
+			}
+			if item.Cmp(expected) != 0 {
+				t.Errorf("item %d with depth %d should be %s, but it is %s", index, depth, expected, item)
+			}
+		}
+	}
+}
+
+func TestAnswer_params(t *testing.T) {
+	_, pk, err := tcpaillier.NewKey(bitSize, 2, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	db := make([]*big.Int, 7)
+	for i := range db {
+		db[i] = big.NewInt(int64(i + 1))
+	}
+	params, err := pir.NewParams(pk, 100, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	q, err := pir.NewQuery(pk, params, 3)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := pir.Answer(pk, db, q); err == nil {
+		t.Errorf("query for A database of 100 items should not be answered on one of %d items", len(db))
+	}
+	// A huge side would make the server run out of memory if it was trusted.
+	q.Side = 1 << 30
+	if _, err := pir.Answer(pk, db, q); err == nil {
+		t.Errorf("query with side %d should not be answered", q.Side)
+	}
+}
+
+func TestLevelKey(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, 3, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	levelPK, err := pir.LevelKey(pk, 1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if levelPK.Fingerprint() == pk.Fingerprint() {
+		t.Errorf("keys with different S should have different fingerprints")
+	}
+	c, _, err := levelPK.Encrypt(big.NewInt(42))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	dec, err := committee(t, shares)(levelPK, c)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if dec.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("decrypted value should be 42, but it is %s", dec)
+	}
+	if _, err := pir.LevelKey(pk, 4); err == nil {
+		t.Errorf("level key with S greater than the one of the key should not be derived")
+	}
+}
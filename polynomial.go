@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"math/big"
 	"strings"
+
+	"github.com/niclabs/tcpaillier/internal/wipe"
 )
 
 // polynomial represents A classic polynomial, with convenience methods useful for
@@ -47,6 +49,13 @@ func createRandomPolynomial(d int, x0, m *big.Int) (polynomial, error) {
 	return poly, nil
 }
 
+// destroy overwrites all the coefficients of the polynomial.
+func (p polynomial) destroy() {
+	for _, coeff := range p {
+		wipe.Int(coeff)
+	}
+}
+
 // eval evaluates A polynomial to x with Horner's method and returns the result.
 func (p polynomial) eval(x *big.Int) *big.Int {
 	y := big.NewInt(0)
@@ -43,3 +43,26 @@ func TestPolynomial_Eval(t *testing.T) {
 		t.Errorf("The evaluations is not providing a correct result")
 	}
 }
+
+func TestPolynomial_destroy(t *testing.T) {
+	p, err := createRandomPolynomial(polynomialTestDegree, big.NewInt(10), big.NewInt(1024))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	words := make([][]big.Word, len(p))
+	for i, coeff := range p {
+		words[i] = coeff.Bits()
+	}
+	p.destroy()
+	for i, coeffWords := range words {
+		for _, word := range coeffWords {
+			if word != 0 {
+				t.Errorf("coefficient %d was not overwritten", i)
+				break
+			}
+		}
+		if p[i].Sign() != 0 {
+			t.Errorf("coefficient %d should be 0", i)
+		}
+	}
+}
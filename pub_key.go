@@ -3,6 +3,7 @@ package tcpaillier
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"math/big"
 )
@@ -27,6 +28,7 @@ type PubKey struct {
 // cached contains the cached PubKey values.
 type cached struct {
 	NPlusOne, NMinusOne, SPlusOne, NToS, NToSPlusOne, BigS *big.Int
+	fingerprint                                            Fingerprint
 }
 
 // Cache initializes the cached values and returns the structure.
@@ -52,10 +54,93 @@ func (pk *PubKey) Cache() *cached {
 			NToS:        nToS,
 			NToSPlusOne: nToSPlusOne,
 		}
+		pk.cached.fingerprint = pk.fingerprint()
 	}
 	return pk.cached
 }
 
+// Validate checks that the public key values are consistent among them. It is called by
+// the key constructors and when A public key or A key share is unmarshaled. The error
+// returned wraps ErrInvalidPubKey.
+func (pk *PubKey) Validate() error {
+	if err := pk.validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPubKey, err)
+	}
+	return nil
+}
+
+// validate returns the first inconsistency of the public key values.
+func (pk *PubKey) validate() error {
+	if pk.N == nil || pk.N.Cmp(one) <= 0 {
+		return fmt.Errorf("N should be greater than 1")
+	}
+	if pk.S < 1 {
+		return fmt.Errorf("S should be at least 1, but it is %d", pk.S)
+	}
+	if pk.L <= 1 {
+		return fmt.Errorf("L should be greater than 1, but it is %d", pk.L)
+	}
+	if pk.K < (pk.L/2+1) || pk.K > pk.L {
+		return fmt.Errorf("K should be between %d and %d, but it is %d", (pk.L/2)+1, pk.L, pk.K)
+	}
+	if len(pk.Vi) != int(pk.L) {
+		return fmt.Errorf("there should be %d verification values Vi, but there are %d", pk.L, len(pk.Vi))
+	}
+	delta := new(big.Int).MulRange(1, int64(pk.L))
+	if pk.Delta == nil || pk.Delta.Cmp(delta) != 0 {
+		return fmt.Errorf("Delta should be L! = %s", delta)
+	}
+	// The cached values are not used, because they could be outdated.
+	nToS := new(big.Int).Exp(pk.N, big.NewInt(int64(pk.S)), nil)
+	nToSPlusOne := new(big.Int).Mul(nToS, pk.N)
+	// Constant = (4*Delta^2)^(-1) mod n^s
+	if pk.Constant == nil || pk.Constant.Sign() <= 0 || pk.Constant.Cmp(nToS) >= 0 {
+		return fmt.Errorf("Constant should be between 1 and N^S")
+	}
+	check := new(big.Int).Mul(delta, delta)
+	check.Lsh(check, 2).Mul(check, pk.Constant).Mod(check, nToS)
+	if check.Cmp(one) != 0 {
+		return fmt.Errorf("Constant should be the inverse of 4*Delta^2 modulo N^S")
+	}
+	if err := pk.validateVerificationValue(pk.V, nToSPlusOne); err != nil {
+		return fmt.Errorf("V is invalid: %v", err)
+	}
+	for i, vi := range pk.Vi {
+		if err := pk.validateVerificationValue(vi, nToSPlusOne); err != nil {
+			return fmt.Errorf("V%d is invalid: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateVerificationValue checks that A verification value is between 1 and N^(S+1),
+// and that it is invertible.
+func (pk *PubKey) validateVerificationValue(v, nToSPlusOne *big.Int) error {
+	if v == nil || v.Sign() <= 0 || v.Cmp(nToSPlusOne) >= 0 {
+		return fmt.Errorf("it should be between 1 and N^(S+1)")
+	}
+	if new(big.Int).GCD(nil, nil, v, pk.N).Cmp(one) != 0 {
+		return fmt.Errorf("it should be coprime with N")
+	}
+	return nil
+}
+
+// plainPubKey is A PubKey without methods, used to unmarshal it.
+type plainPubKey PubKey
+
+// UnmarshalJSON decodes A public key and validates it.
+func (pk *PubKey) UnmarshalJSON(b []byte) error {
+	var decoded PubKey
+	if err := json.Unmarshal(b, (*plainPubKey)(&decoded)); err != nil {
+		return err
+	}
+	if err := decoded.Validate(); err != nil {
+		return err
+	}
+	*pk = decoded
+	return nil
+}
+
 // Encrypt encrypts A message and returns its encryption as A big Integer c and the random number r used.
 // If there is an error, it returns A nil integer as c.
 func (pk *PubKey) Encrypt(message *big.Int) (c, r *big.Int, err error) {
@@ -122,13 +207,13 @@ func (pk *PubKey) Add(cList ...*big.Int) (sum *big.Int, err error) {
 	}
 	cache := pk.Cache()
 	nToSPlusOne := cache.NToSPlusOne
-	sum = new(big.Int).Set(cList[0])
-	for i := 1; i < len(cList); i++ {
-		ci := cList[i]
-		if ci.Cmp(nToSPlusOne) >= 0 || ci.Cmp(zero) < 1 {
-			err = fmt.Errorf("CAlpha%d must be between 1 (inclusive) and N^(s+1) (exclusive)", i+1)
+	for i, ci := range cList {
+		if err = pk.validateCiphertext(fmt.Sprintf("c%d", i+1), ci); err != nil {
 			return
 		}
+	}
+	sum = new(big.Int).Set(cList[0])
+	for _, ci := range cList[1:] {
 		sum.Mul(sum, ci)
 		sum.Mod(sum, nToSPlusOne)
 	}
@@ -153,8 +238,7 @@ func (pk *PubKey) Multiply(c *big.Int, alpha *big.Int) (mul, gamma *big.Int, err
 func (pk *PubKey) MultiplyFixed(c *big.Int, alpha, gamma *big.Int) (mul *big.Int, err error) {
 	cache := pk.Cache()
 	nToSPlusOne := cache.NToSPlusOne
-	if c.Cmp(nToSPlusOne) >= 0 || c.Cmp(zero) < 0 {
-		err = fmt.Errorf("c must be between 0 (inclusive) and N^(s+1) (exclusive)")
+	if err = pk.validateCiphertext("c", c); err != nil {
 		return
 	}
 	preMul := new(big.Int).Exp(c, alpha, nToSPlusOne)
@@ -173,10 +257,28 @@ func (pk *PubKey) ReRand(c, r *big.Int) (reRand *big.Int, err error) {
 	return
 }
 
+// ReRandWithProof rerandomizes A value using A random number, and returns it with A ZKProof
+// that the new value encrypts the same message.
+func (pk *PubKey) ReRandWithProof(c *big.Int) (reRand *big.Int, proof *ReRandZK, err error) {
+	r, err := pk.RandomModNToSPlusOneStar()
+	if err != nil {
+		return
+	}
+	reRand, err = pk.ReRand(c, r)
+	if err != nil {
+		return
+	}
+	proof, err = pk.ReRandProof(c, reRand, r)
+	return
+}
+
 // MultiplyWithProof multiplies an encrypted value by A constant and returns it with A ZKProof of the
 // multiplication. It returns an error if it is not able to Multiply the value.
 func (pk *PubKey) MultiplyWithProof(encrypted *big.Int, constant *big.Int) (result *big.Int, proof *MulZK, err error) {
 	result, gamma, err := pk.Multiply(encrypted, constant)
+	if err != nil {
+		return
+	}
 	s, err := pk.RandomModNToSPlusOneStar()
 	if err != nil {
 		return
@@ -192,56 +294,126 @@ func (pk *PubKey) MultiplyWithProof(encrypted *big.Int, constant *big.Int) (resu
 // CombineShares joins partial decryptions of A value and returns A decrypted value.
 // It checks that the number of values is equal or more than the threshold.
 func (pk *PubKey) CombineShares(shares ...*DecryptionShare) (dec *big.Int, err error) {
-	n := pk.N
+	shares, err = pk.thresholdShares(shares)
+	if err != nil {
+		return
+	}
+	indexes := make([]uint8, len(shares))
+	for i, share := range shares {
+		indexes[i] = share.Index
+	}
+	dec = pk.combine(shares, pk.lagrangeExponents(indexes))
+	return
+}
+
+// thresholdShares checks that there are enough shares to decrypt a value and returns
+// the first K of them. It returns an error if any of those shares is repeated.
+func (pk *PubKey) thresholdShares(shares []*DecryptionShare) ([]*DecryptionShare, error) {
 	k := int(pk.K)
-	cache := pk.Cache()
-	nToSPlusOne := cache.NToSPlusOne
 
 	if len(shares) < k {
-		err = fmt.Errorf("needed %d shares to decrypt, but got %d", pk.K, len(shares))
-		return
+		return nil, &InsufficientSharesError{Have: len(shares), Need: k}
 	}
 
 	shares = shares[:pk.K]
 
 	// Check for repeated shares
 	indexes := make(map[uint8]int)
+	fp := pk.Fingerprint()
 	for i, share := range shares {
+		if err := checkFingerprint(fmt.Sprintf("share %d", share.Index), share.Fingerprint, fp); err != nil {
+			return nil, err
+		}
 		if j, ok := indexes[share.Index]; ok {
-			err = fmt.Errorf("share %d repeated on indexes %d and %d", share.Index, i, j)
-			return
+			return nil, fmt.Errorf("%w: share %d on indexes %d and %d", ErrRepeatedShare, share.Index, i, j)
+		}
+		if err := pk.validateCiphertext(fmt.Sprintf("share %d", share.Index), share.Ci); err != nil {
+			return nil, err
 		}
 		indexes[share.Index] = i
 	}
+	return shares, nil
+}
 
-	cPrime := new(big.Int).Set(one)
-
-	for _, share := range shares {
+// lagrangeExponents returns the exponents used to combine the shares with the indexes
+// provided. The exponent of each share is 2*Delta*lambda_i, where lambda_i is its Lagrange
+// coefficient evaluated in 0.
+func (pk *PubKey) lagrangeExponents(indexes []uint8) []*big.Int {
+	exps := make([]*big.Int, len(indexes))
+	for i, index := range indexes {
 		num := new(big.Int).Set(pk.Delta) // Lambda is multiplied by two, we are doing that now.
 		den := new(big.Int).Set(one)
-		for _, sharePrime := range shares {
-			if share.Index != sharePrime.Index {
-				num.Mul(num, big.NewInt(int64(sharePrime.Index)))
-				den.Mul(den, big.NewInt(int64(sharePrime.Index)-int64(share.Index)))
+		for _, indexPrime := range indexes {
+			if index != indexPrime {
+				num.Mul(num, big.NewInt(int64(indexPrime)))
+				den.Mul(den, big.NewInt(int64(indexPrime)-int64(index)))
 			}
 		}
 		lambda2 := new(big.Int)
 		lambda2.Mul(num, two).Quo(lambda2, den)
-		CiToLambda2 := new(big.Int).Exp(share.Ci, lambda2, nToSPlusOne)
+		exps[i] = lambda2
+	}
+	return exps
+}
+
+// combine joins the shares using the exponents provided, and returns the decrypted value.
+func (pk *PubKey) combine(shares []*DecryptionShare, exps []*big.Int) *big.Int {
+	cache := pk.Cache()
+	nToSPlusOne := cache.NToSPlusOne
+
+	cPrime := new(big.Int).Set(one)
+
+	for i, share := range shares {
+		CiToLambda2 := new(big.Int).Exp(share.Ci, exps[i], nToSPlusOne)
 		cPrime.Mul(cPrime, CiToLambda2).Mod(cPrime, nToSPlusOne)
 	}
 
-	l := new(big.Int)
-	l.Sub(cPrime, one).Div(l, n)
-	bigDec := new(big.Int).Mul(pk.Constant, l)
-	bigDec.Mod(bigDec, n)
-	dec = bigDec
-	return
+	return pk.decode(cPrime)
+}
+
+// decode returns the decrypted value from the product of the shares
+// to the power of their Lagrange exponents.
+func (pk *PubKey) decode(cPrime *big.Int) *big.Int {
+	n := pk.N
+	// The exponent i of cPrime = (n+1)^i mod n^(s+1) is computed modulo n, n^2, ..., n^s,
+	// using the algorithm of Damgård and Jurik.
+	i := new(big.Int)
+	nToJ := big.NewInt(1)
+	for j := 1; j <= int(pk.S); j++ {
+		nToJ.Mul(nToJ, n)
+		t1 := new(big.Int).Mod(cPrime, new(big.Int).Mul(nToJ, n))
+		t1.Sub(t1, one).Div(t1, n)
+		t2 := new(big.Int).Set(i)
+		kFact := big.NewInt(1)
+		nToKMinusOne := big.NewInt(1)
+		for k := 2; k <= j; k++ {
+			i.Sub(i, one)
+			t2.Mul(t2, i).Mod(t2, nToJ)
+			kFact.Mul(kFact, big.NewInt(int64(k)))
+			nToKMinusOne.Mul(nToKMinusOne, n)
+			// t1 = t1 - t2*n^(k-1)/k! mod n^j
+			sub := new(big.Int).ModInverse(kFact, nToJ)
+			sub.Mul(sub, t2).Mul(sub, nToKMinusOne)
+			t1.Sub(t1, sub)
+		}
+		i.Mod(t1, nToJ)
+	}
+	dec := new(big.Int).Mul(pk.Constant, i)
+	dec.Mod(dec, nToJ)
+	return dec
 }
 
 // EncryptProof returns A ZK Proof of an encrypted message c. s is the random number
 // used to EncryptFixed message to c.
 func (pk *PubKey) EncryptProof(message *big.Int, c, s *big.Int) (zk *EncryptZK, err error) {
+	return pk.encryptProof(message, c, s, func(b *big.Int) *big.Int {
+		return encryptChallenge(c, b)
+	})
+}
+
+// encryptProof returns A ZK Proof of an encrypted message c, using the challenge function
+// provided to get the challenge from the commitment b.
+func (pk *PubKey) encryptProof(message *big.Int, c, s *big.Int, challenge func(b *big.Int) *big.Int) (zk *EncryptZK, err error) {
 	cache := pk.Cache()
 	nToSPlusOne := cache.NToSPlusOne
 	nPlusOne := cache.NPlusOne
@@ -264,12 +436,7 @@ func (pk *PubKey) EncryptProof(message *big.Int, c, s *big.Int) (zk *EncryptZK,
 	b := new(big.Int)
 	b.Mul(nPlusOneToX, uToN).Mod(b, nToSPlusOne)
 
-	hash := sha256.New()
-	hash.Write(c.Bytes())
-	hash.Write(b.Bytes())
-	eBytes := hash.Sum(nil)
-
-	e := new(big.Int).SetBytes(eBytes)
+	e := challenge(b)
 
 	eAlpha := new(big.Int).Mul(e, alpha)
 
@@ -299,17 +466,18 @@ func (pk *PubKey) MultiplyProof(ca, cAlpha, d, alpha, s, gamma *big.Int) (zk *Mu
 	nPlusOne := cache.NPlusOne
 	nToS := cache.NToS
 
-	if ca.Cmp(nToSPlusOne) >= 0 || ca.Cmp(zero) < 0 {
-		err = fmt.Errorf("ca must be between 1 (inclusive) and N^(s+1) (exclusive)")
+	if err = pk.validateCiphertext("ca", ca); err != nil {
 		return
 	}
-
-	if cAlpha.Cmp(nToSPlusOne) >= 0 || cAlpha.Cmp(zero) < 0 {
-		err = fmt.Errorf("CAlpha must be between 1 (inclusive) and N^(s+1) (exclusive)")
+	if err = pk.validateCiphertext("CAlpha", cAlpha); err != nil {
+		return
+	}
+	if err = pk.validateCiphertext("d", d); err != nil {
 		return
 	}
 
-	x, err := pk.RandomModN()
+	// W is reduced modulo n^s, so x must be uniform modulo n^s to hide e*alpha.
+	x, err := pk.RandomModNToS()
 	if err != nil {
 		return
 	}
@@ -371,10 +539,216 @@ func (pk *PubKey) MultiplyProof(ca, cAlpha, d, alpha, s, gamma *big.Int) (zk *Mu
 	return
 }
 
+// EqualityProof returns A ZKProof that c1 and c2 are both encryptions of message with this
+// public key. r1 and r2 are the random numbers used to encrypt c1 and c2 respectively.
+func (pk *PubKey) EqualityProof(message, c1, r1, c2, r2 *big.Int) (zk *EqualityZK, err error) {
+	return pk.CrossEqualityProof(pk, message, c1, r1, c2, r2)
+}
+
+// CrossEqualityProof returns A ZKProof that c1, encrypted with this public key, and c2,
+// encrypted with other public key, are both encryptions of message. r1 and r2 are the random
+// numbers used to encrypt c1 and c2 respectively. The message must be lower than N^s for both keys.
+func (pk *PubKey) CrossEqualityProof(other *PubKey, message, c1, r1, c2, r2 *big.Int) (zk *EqualityZK, err error) {
+	cache1, cache2 := pk.Cache(), other.Cache()
+
+	maxMessage := minNToS(pk, other)
+	if message.Sign() < 0 || message.Cmp(maxMessage) >= 0 {
+		err = fmt.Errorf("message must be between 0 (inclusive) and N^s (exclusive) for both keys")
+		return
+	}
+	if err = pk.validateCiphertext("c1", c1); err != nil {
+		return
+	}
+	if err = other.validateCiphertext("c2", c2); err != nil {
+		return
+	}
+
+	// W is not reduced, so x must be big enough to hide e*message.
+	x, err := RandomInt(equalityBits(pk, other))
+	if err != nil {
+		return
+	}
+
+	u1, err := pk.RandomModNToSPlusOneStar()
+	if err != nil {
+		return
+	}
+
+	u2, err := other.RandomModNToSPlusOneStar()
+	if err != nil {
+		return
+	}
+
+	// (n+1)^x * u^(n^s) % n^(s+1), for both keys.
+	a1, err := pk.EncryptFixed(x, u1)
+	if err != nil {
+		return
+	}
+	a2, err := other.EncryptFixed(x, u2)
+	if err != nil {
+		return
+	}
+
+	e := equalityChallenge(pk, other, c1, c2, a1, a2)
+
+	w := new(big.Int).Mul(e, message)
+	w.Add(w, x)
+
+	r1ToE := new(big.Int).Exp(r1, e, cache1.NToSPlusOne)
+	z1 := new(big.Int)
+	z1.Mul(u1, r1ToE).Mod(z1, cache1.NToSPlusOne)
+
+	r2ToE := new(big.Int).Exp(r2, e, cache2.NToSPlusOne)
+	z2 := new(big.Int)
+	z2.Mul(u2, r2ToE).Mod(z2, cache2.NToSPlusOne)
+
+	zk = &EqualityZK{
+		A1: a1,
+		A2: a2,
+		W:  w,
+		Z1: z1,
+		Z2: z2,
+	}
+	return
+}
+
+// ReRandProof returns A ZKProof that reRand is A rerandomization of c. r is the random
+// number used to rerandomize it, so reRand = c * r^(n^s) % n^(s+1).
+func (pk *PubKey) ReRandProof(c, reRand, r *big.Int) (zk *ReRandZK, err error) {
+	if err = pk.validateCiphertext("c", c); err != nil {
+		return
+	}
+	if err = pk.validateCiphertext("reRand", reRand); err != nil {
+		return
+	}
+	cache := pk.Cache()
+	nToSPlusOne := cache.NToSPlusOne
+	nToS := cache.NToS
+
+	u, err := pk.RandomModNToSPlusOneStar()
+	if err != nil {
+		return
+	}
+
+	a := new(big.Int).Exp(u, nToS, nToSPlusOne)
+
+	hash := sha256.New()
+	hash.Write(c.Bytes())
+	hash.Write(reRand.Bytes())
+	hash.Write(a.Bytes())
+	e := new(big.Int).SetBytes(hash.Sum(nil))
+
+	rToE := new(big.Int).Exp(r, e, nToSPlusOne)
+	z := new(big.Int)
+	z.Mul(u, rToE).Mod(z, nToSPlusOne)
+
+	zk = &ReRandZK{
+		A: a,
+		Z: z,
+	}
+	return
+}
+
+// EncryptBitWithProof encrypts A bit, that must be 0 or 1, and returns its encryption with
+// A ZKProof that the encrypted value is 0 or 1.
+func (pk *PubKey) EncryptBitWithProof(bit *big.Int) (c *big.Int, proof *BitZK, err error) {
+	r, err := pk.RandomModNToSPlusOneStar()
+	if err != nil {
+		return
+	}
+	c, err = pk.EncryptFixed(bit, r)
+	if err != nil {
+		return
+	}
+	proof, err = pk.BitProof(bit, c, r)
+	return
+}
+
+// BitProof returns A ZKProof that c is an encryption of bit, without revealing it. bit
+// must be 0 or 1, and r is the random number used to encrypt it.
+func (pk *PubKey) BitProof(bit, c, r *big.Int) (zk *BitZK, err error) {
+	if bit.Cmp(zero) != 0 && bit.Cmp(one) != 0 {
+		err = fmt.Errorf("bit must be 0 or 1")
+		return
+	}
+	if err = pk.validateCiphertext("c", c); err != nil {
+		return
+	}
+	cache := pk.Cache()
+	nToSPlusOne := cache.NToSPlusOne
+	nToS := cache.NToS
+
+	// u_0 = c and u_1 = c/(n+1) are n^s-th residues if c encrypts 0 or 1 respectively.
+	us := pk.bitResidues(c)
+	real := int(bit.Int64())
+	fake := 1 - real
+
+	as := make([]*big.Int, 2)
+	es := make([]*big.Int, 2)
+	zs := make([]*big.Int, 2)
+
+	// The proof for the other value is simulated: A = Z^(n^s) * u^(-E) % n^(s+1)
+	es[fake], err = RandomInt(sha256.Size * 8)
+	if err != nil {
+		return
+	}
+	zs[fake], err = pk.RandomModNToSPlusOneStar()
+	if err != nil {
+		return
+	}
+	zToNToS := new(big.Int).Exp(zs[fake], nToS, nToSPlusOne)
+	uToMinusE := new(big.Int).Exp(us[fake], new(big.Int).Neg(es[fake]), nToSPlusOne)
+	if uToMinusE == nil {
+		err = fmt.Errorf("encrypted value is not invertible")
+		return
+	}
+	as[fake] = new(big.Int).Mul(zToNToS, uToMinusE)
+	as[fake].Mod(as[fake], nToSPlusOne)
+
+	rho, err := pk.RandomModNToSPlusOneStar()
+	if err != nil {
+		return
+	}
+	as[real] = new(big.Int).Exp(rho, nToS, nToSPlusOne)
+
+	e := bitChallenge(c, as[0], as[1])
+	es[real] = new(big.Int).Sub(e, es[fake])
+	es[real].Mod(es[real], bitChallengeMod)
+
+	rToE := new(big.Int).Exp(r, es[real], nToSPlusOne)
+	zs[real] = new(big.Int).Mul(rho, rToE)
+	zs[real].Mod(zs[real], nToSPlusOne)
+
+	zk = &BitZK{
+		A0: as[0],
+		A1: as[1],
+		E0: es[0],
+		E1: es[1],
+		Z0: zs[0],
+		Z1: zs[1],
+	}
+	return
+}
+
+// bitResidues returns c and c/(n+1), which are n^s-th residues modulo n^(s+1) if
+// c is an encryption of 0 or 1 respectively.
+func (pk *PubKey) bitResidues(c *big.Int) []*big.Int {
+	cache := pk.Cache()
+	nToSPlusOne := cache.NToSPlusOne
+	nPlusOneInv := new(big.Int).ModInverse(cache.NPlusOne, nToSPlusOne)
+	u1 := new(big.Int).Mul(c, nPlusOneInv)
+	u1.Mod(u1, nToSPlusOne)
+	return []*big.Int{c, u1}
+}
+
 func (pk *PubKey) RandomModN() (r *big.Int, err error) {
 	return rand.Int(rand.Reader, pk.N)
 }
 
+func (pk *PubKey) RandomModNToS() (r *big.Int, err error) {
+	return rand.Int(rand.Reader, pk.Cache().NToS)
+}
+
 func (pk *PubKey) RandomModNToSPlusOneStar() (r *big.Int, err error) {
 	cache := pk.Cache()
 	nToSPlusOneMinusOne := new(big.Int).Sub(cache.NToSPlusOne, one)
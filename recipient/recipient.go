@@ -0,0 +1,193 @@
+// Package recipient encrypts decryption shares to the key of the party that requested
+// them, so only that party can combine them. The coordinators and other intermediaries
+// that forward the shares learn neither the shares nor the decrypted value.
+//
+// Shares are sealed with NaCl anonymous boxes (X25519, XSalsa20 and Poly1305). A sealed
+// share contains the decryption share, its proof and the encrypted value it decrypts, so
+// the recipient checks that it was computed for the value it asked for.
+package recipient
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/internal/wipe"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// KeySize is the size in bytes of the public and private keys of A recipient.
+const KeySize = 32
+
+// PublicKey is the key shares are sealed to.
+type PublicKey [KeySize]byte
+
+// PrivateKey is the key that opens the shares sealed to its public key.
+type PrivateKey struct {
+	PublicKey PublicKey
+	Secret    [KeySize]byte
+}
+
+// SealedShare is A decryption share and its proof sealed to A recipient. Fingerprint and
+// Index are the ones of the share, and they are not secret, so intermediaries can discard
+// shares of other keys or repeated ones without opening them.
+type SealedShare struct {
+	Fingerprint tcpaillier.Fingerprint
+	Index       uint8
+	Box         []byte
+}
+
+// sealed is the content of the box of A sealed share.
+type sealed struct {
+	C     *big.Int
+	Share *tcpaillier.DecryptionShare
+	Proof *tcpaillier.DecryptShareZK
+}
+
+// MarshalText encodes the public key in base64.
+func (pub PublicKey) MarshalText() ([]byte, error) {
+	return []byte(base64.StdEncoding.EncodeToString(pub[:])), nil
+}
+
+// UnmarshalText decodes A public key encoded in base64.
+func (pub *PublicKey) UnmarshalText(b []byte) error {
+	decoded, err := base64.StdEncoding.DecodeString(string(b))
+	if err != nil {
+		return err
+	}
+	if len(decoded) != KeySize {
+		return fmt.Errorf("recipient public key should have %d bytes, but it has %d", KeySize, len(decoded))
+	}
+	copy(pub[:], decoded)
+	return nil
+}
+
+// GenerateKey returns A new private key for A recipient.
+func GenerateKey() (priv *PrivateKey, err error) {
+	pub, secret, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return
+	}
+	priv = &PrivateKey{
+		PublicKey: *pub,
+		Secret:    *secret,
+	}
+	return
+}
+
+// Destroy overwrites the secret value of the private key.
+func (priv *PrivateKey) Destroy() {
+	for i := range priv.Secret {
+		priv.Secret[i] = 0
+	}
+}
+
+// Seal seals the decryption share of the encrypted value c and its proof to the recipient.
+func Seal(c *big.Int, share *tcpaillier.DecryptionShare, proof *tcpaillier.DecryptShareZK, to *PublicKey) (ss *SealedShare, err error) {
+	if c == nil || share == nil || proof == nil {
+		err = fmt.Errorf("encrypted value, share and proof are required")
+		return
+	}
+	b, err := json.Marshal(&sealed{
+		C:     c,
+		Share: share,
+		Proof: proof,
+	})
+	if err != nil {
+		return
+	}
+	defer wipe.Bytes(b)
+	out, err := box.SealAnonymous(nil, b, (*[KeySize]byte)(to), rand.Reader)
+	if err != nil {
+		return
+	}
+	ss = &SealedShare{
+		Fingerprint: share.Fingerprint,
+		Index:       share.Index,
+		Box:         out,
+	}
+	return
+}
+
+// SealWith decrypts partially c with the signer and seals the share and its proof to the
+// recipient.
+func SealWith(signer tcpaillier.ShareSigner, c *big.Int, to *PublicKey) (ss *SealedShare, err error) {
+	share, err := signer.PartialDecrypt(c)
+	if err != nil {
+		return
+	}
+	proof, err := signer.PartialDecryptProof(c, share)
+	if err != nil {
+		return
+	}
+	return Seal(c, share, proof, to)
+}
+
+// Open opens A sealed share with the private key, and it verifies that it is A valid
+// decryption share of c for the public key.
+func (priv *PrivateKey) Open(pk *tcpaillier.PubKey, c *big.Int, ss *SealedShare) (share *tcpaillier.DecryptionShare, err error) {
+	if ss == nil {
+		err = fmt.Errorf("sealed share is not defined")
+		return
+	}
+	b, ok := box.OpenAnonymous(nil, ss.Box, (*[KeySize]byte)(&priv.PublicKey), &priv.Secret)
+	if !ok {
+		err = fmt.Errorf("cannot open sealed share %d", ss.Index)
+		return
+	}
+	defer wipe.Bytes(b)
+	var content sealed
+	if err = json.Unmarshal(b, &content); err != nil {
+		err = fmt.Errorf("cannot decode sealed share %d: %v", ss.Index, err)
+		return
+	}
+	if content.C == nil || content.C.Cmp(c) != 0 {
+		err = fmt.Errorf("sealed share %d is for another encrypted value", ss.Index)
+		return
+	}
+	if content.Share == nil || content.Share.Index != ss.Index || content.Share.Fingerprint != ss.Fingerprint {
+		err = fmt.Errorf("sealed share %d does not match its content", ss.Index)
+		return
+	}
+	if err = content.Proof.Verify(pk, c, content.Share); err != nil {
+		err = fmt.Errorf("sealed share %d is invalid: %w", ss.Index, err)
+		return
+	}
+	share = content.Share
+	return
+}
+
+// Combine opens the sealed shares of c and combines the first K valid ones. The shares that
+// cannot be opened or are not valid are skipped, and their errors are returned only if
+// there are not enough valid shares.
+func (priv *PrivateKey) Combine(pk *tcpaillier.PubKey, c *big.Int, sealedShares ...*SealedShare) (dec *big.Int, err error) {
+	var shares []*tcpaillier.DecryptionShare
+	var errs []error
+	used := make(map[uint8]bool)
+	for _, ss := range sealedShares {
+		if len(shares) == int(pk.K) {
+			break
+		}
+		share, err := priv.Open(pk, c, ss)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if used[share.Index] {
+			continue
+		}
+		used[share.Index] = true
+		shares = append(shares, share)
+	}
+	if len(shares) < int(pk.K) {
+		err = &tcpaillier.InsufficientSharesError{Have: len(shares), Need: int(pk.K)}
+		if len(errs) > 0 {
+			err = fmt.Errorf("%d sealed shares are invalid (first: %v): %w", len(errs), errs[0], err)
+		}
+		return
+	}
+	return pk.CombineShares(shares...)
+}
@@ -0,0 +1,90 @@
+package recipient_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/recipient"
+)
+
+const k = 2
+const l = 3
+const s = 1
+
+const bitSize = 256
+
+func TestPrivateKey_Combine(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	priv, err := recipient.GenerateKey()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	msg := big.NewInt(4321)
+	c, _, err := pk.Encrypt(msg)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	sealed := make([]*recipient.SealedShare, len(shares))
+	for i, share := range shares {
+		if sealed[i], err = recipient.SealWith(share, c, &priv.PublicKey); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	// The first share is modified, so it is skipped.
+	sealed[0].Box[len(sealed[0].Box)-1] ^= 1
+	dec, err := priv.Combine(pk, c, sealed...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if dec.Cmp(msg) != 0 {
+		t.Errorf("decrypted value should be %s, but it is %s", msg, dec)
+	}
+	if _, err := priv.Combine(pk, c, sealed[:2]...); err == nil {
+		t.Errorf("combining one valid share should fail")
+	}
+}
+
+func TestPrivateKey_Open(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	priv, err := recipient.GenerateKey()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	other, err := recipient.GenerateKey()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	c, _, err := pk.Encrypt(big.NewInt(1))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	c2, _, err := pk.Encrypt(big.NewInt(2))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	sealed, err := recipient.SealWith(shares[0], c, &priv.PublicKey)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := priv.Open(pk, c, sealed); err != nil {
+		t.Errorf("sealed share should be opened by its recipient: %v", err)
+	}
+	if _, err := other.Open(pk, c, sealed); err == nil {
+		t.Errorf("sealed share should not be opened by another recipient")
+	}
+	if _, err := priv.Open(pk, c2, sealed); err == nil {
+		t.Errorf("sealed share should not be accepted for another encrypted value")
+	}
+	relabeled := *sealed
+	relabeled.Index++
+	if _, err := priv.Open(pk, c, &relabeled); err == nil {
+		t.Errorf("sealed share with another index should not be accepted")
+	}
+}
@@ -0,0 +1,339 @@
+// Package shuffle implements A verifiable shuffle (mix-net) of threshold Paillier
+// ciphertexts. A shuffle permutes A list of ciphertexts and rerandomizes each one of
+// them with PubKey.ReRand, and it returns A proof that the output is A rerandomized
+// permutation of the input, without revealing the permutation.
+//
+// The proof uses the cut-and-choose technique of Sako and Kilian, made non-interactive
+// with the Fiat-Shamir heuristic: the prover computes A number of shadow shuffles of
+// the input, and for each one of them it opens either the shuffle from the input to the
+// shadow or the one from the shadow to the output, depending on the bits of A hash. Its
+// size is linear on the number of rounds, but it only needs rerandomizations to be
+// built and verified.
+package shuffle
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+// DefaultRounds is the number of shadow shuffles used by A Shuffler without rounds, and
+// the minimum number of them accepted by Proof.Verify. A cheating mixer is accepted with
+// probability 2^-rounds.
+const DefaultRounds = 128
+
+// Opening represents the permutation and random values of one of the shadow shuffles
+// of A proof. A shuffle maps the position j of its output to the position Permutation[j]
+// of its input, rerandomized with Randoms[j].
+type Opening struct {
+	Permutation []int
+	Randoms     []*big.Int
+}
+
+// Proof represents A proof of shuffle. Shadows are the shadow shuffles of the input, and
+// Openings[i] opens the i-th shadow shuffle from the input or to the output.
+type Proof struct {
+	Shadows  [][]*big.Int
+	Openings []*Opening
+}
+
+// Mixer represents A party that shuffles A list of ciphertexts and proves it.
+type Mixer interface {
+	Mix(pk *tcpaillier.PubKey, in []*big.Int) (out []*big.Int, proof *Proof, err error)
+}
+
+// Shuffler is A Mixer that shuffles the ciphertexts locally, using Rounds shadow shuffles
+// in its proofs. If Rounds is zero, it uses DefaultRounds, and it cannot be lower than it.
+type Shuffler struct {
+	Rounds int
+}
+
+// Mix permutes and rerandomizes A list of ciphertexts, and returns them with A proof of shuffle.
+func (sh *Shuffler) Mix(pk *tcpaillier.PubKey, in []*big.Int) (out []*big.Int, proof *Proof, err error) {
+	if len(in) == 0 {
+		err = fmt.Errorf("empty list of ciphertexts")
+		return
+	}
+	rounds := sh.Rounds
+	if rounds == 0 {
+		rounds = DefaultRounds
+	}
+	if rounds < DefaultRounds {
+		err = fmt.Errorf("rounds should be at least %d, but they are %d", DefaultRounds, rounds)
+		return
+	}
+	nToSPlusOne := pk.Cache().NToSPlusOne
+
+	main, err := randomShuffle(pk, len(in))
+	if err != nil {
+		return
+	}
+	out, err = apply(pk, in, main)
+	if err != nil {
+		return
+	}
+
+	shadows := make([]*Opening, rounds)
+	proof = &Proof{
+		Shadows:  make([][]*big.Int, rounds),
+		Openings: make([]*Opening, rounds),
+	}
+	for i := range shadows {
+		shadows[i], err = randomShuffle(pk, len(in))
+		if err != nil {
+			return
+		}
+		proof.Shadows[i], err = apply(pk, in, shadows[i])
+		if err != nil {
+			return
+		}
+	}
+
+	bits := challenge(pk, in, out, proof.Shadows)
+	for i, shadow := range shadows {
+		if bits[i] == 0 {
+			proof.Openings[i] = shadow
+			continue
+		}
+		// out[j] = shadow[inv[main[j]]] * (r[j] / t[inv[main[j]]])^(n^s)
+		inv := make([]int, len(in))
+		for j, k := range shadow.Permutation {
+			inv[k] = j
+		}
+		opening := &Opening{
+			Permutation: make([]int, len(in)),
+			Randoms:     make([]*big.Int, len(in)),
+		}
+		for j, k := range main.Permutation {
+			pos := inv[k]
+			tInv := new(big.Int).ModInverse(shadow.Randoms[pos], nToSPlusOne)
+			if tInv == nil {
+				err = fmt.Errorf("random value is not invertible")
+				return
+			}
+			opening.Permutation[j] = pos
+			opening.Randoms[j] = tInv.Mul(tInv, main.Randoms[j]).Mod(tInv, nToSPlusOne)
+		}
+		proof.Openings[i] = opening
+	}
+	return
+}
+
+// Verify verifies the proof of shuffle. The extra values are the input and the output of
+// the shuffle, as lists of ciphertexts. The proof must have at least DefaultRounds shadow
+// shuffles, so the mixer cannot choose its own probability of cheating.
+func (proof *Proof) Verify(pk *tcpaillier.PubKey, vals ...interface{}) error {
+	if proof == nil {
+		return fmt.Errorf("proof of shuffle is not defined")
+	}
+	if len(vals) != 2 {
+		return fmt.Errorf("the extra values for verification should be the input and the output of the shuffle")
+	}
+	in, ok := vals[0].([]*big.Int)
+	if !ok {
+		return fmt.Errorf("cannot cast first verification value as A []*big.Int")
+	}
+	out, ok := vals[1].([]*big.Int)
+	if !ok {
+		return fmt.Errorf("cannot cast second verification value as A []*big.Int")
+	}
+	if len(in) == 0 || len(in) != len(out) {
+		return fmt.Errorf("input and output should have the same positive length")
+	}
+	if len(proof.Shadows) < DefaultRounds || len(proof.Shadows) != len(proof.Openings) {
+		return fmt.Errorf("proof should have the same number of shadows and openings, and at least %d of them", DefaultRounds)
+	}
+	if !defined(in) || !defined(out) {
+		return fmt.Errorf("input and output should not have undefined ciphertexts")
+	}
+	for _, shadow := range proof.Shadows {
+		if len(shadow) != len(in) || !defined(shadow) {
+			return fmt.Errorf("shadow shuffles should have the same length as the input and no undefined ciphertexts")
+		}
+	}
+	bits := challenge(pk, in, out, proof.Shadows)
+	for i, opening := range proof.Openings {
+		var err error
+		if bits[i] == 0 {
+			err = check(pk, in, proof.Shadows[i], opening)
+		} else {
+			err = check(pk, proof.Shadows[i], out, opening)
+		}
+		if err != nil {
+			return fmt.Errorf("round %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// Stage represents the output of one mixer in A chain and its proof of shuffle.
+type Stage struct {
+	Output []*big.Int
+	Proof  *Proof
+}
+
+// MixerError is returned when A mixer of A chain fails or returns an invalid shuffle.
+type MixerError struct {
+	Index int
+	Err   error
+}
+
+func (err *MixerError) Error() string {
+	return fmt.Sprintf("mixer %d: %v", err.Index, err.Err)
+}
+
+// RunChain shuffles A list of ciphertexts with A chain of mixers, where each mixer shuffles
+// the output of the previous one. The proof of each mixer is verified before the next one
+// runs. If A mixer fails or its proof is invalid, it returns A *MixerError with its index
+// and the stages completed before it.
+func RunChain(pk *tcpaillier.PubKey, in []*big.Int, mixers ...Mixer) (out []*big.Int, stages []*Stage, err error) {
+	out = in
+	for i, mixer := range mixers {
+		next, proof, mixErr := mixer.Mix(pk, out)
+		if mixErr == nil {
+			mixErr = proof.Verify(pk, out, next)
+		}
+		if mixErr != nil {
+			out = nil
+			err = &MixerError{Index: i, Err: mixErr}
+			return
+		}
+		stages = append(stages, &Stage{Output: next, Proof: proof})
+		out = next
+	}
+	return
+}
+
+// VerifyChain verifies every stage of A chain of mixers that started with the input provided.
+// If A stage is invalid, it returns A *MixerError with its index.
+func VerifyChain(pk *tcpaillier.PubKey, in []*big.Int, stages []*Stage) error {
+	for i, stage := range stages {
+		if stage == nil {
+			return &MixerError{Index: i, Err: fmt.Errorf("stage is not defined")}
+		}
+		if err := stage.Proof.Verify(pk, in, stage.Output); err != nil {
+			return &MixerError{Index: i, Err: err}
+		}
+		in = stage.Output
+	}
+	return nil
+}
+
+// randomShuffle returns A random permutation of n elements and n random values to rerandomize them.
+func randomShuffle(pk *tcpaillier.PubKey, n int) (shuffle *Opening, err error) {
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	// Fisher-Yates shuffle
+	for i := n - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return nil, err
+		}
+		perm[i], perm[j.Int64()] = perm[j.Int64()], perm[i]
+	}
+	randoms := make([]*big.Int, n)
+	for i := range randoms {
+		randoms[i], err = pk.RandomModNToSPlusOneStar()
+		if err != nil {
+			return
+		}
+	}
+	shuffle = &Opening{
+		Permutation: perm,
+		Randoms:     randoms,
+	}
+	return
+}
+
+// apply returns the list of ciphertexts shuffled.
+func apply(pk *tcpaillier.PubKey, in []*big.Int, shuffle *Opening) (out []*big.Int, err error) {
+	out = make([]*big.Int, len(in))
+	for j, k := range shuffle.Permutation {
+		out[j], err = pk.ReRand(in[k], shuffle.Randoms[j])
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// check returns an error if out is not the shuffle of in opened.
+func check(pk *tcpaillier.PubKey, in, out []*big.Int, opening *Opening) error {
+	if opening == nil || len(opening.Permutation) != len(in) || len(opening.Randoms) != len(in) {
+		return fmt.Errorf("opening should have %d elements", len(in))
+	}
+	used := make([]bool, len(in))
+	for _, k := range opening.Permutation {
+		if k < 0 || k >= len(in) || used[k] {
+			return fmt.Errorf("opening is not a permutation")
+		}
+		used[k] = true
+	}
+	for _, r := range opening.Randoms {
+		if r == nil {
+			return fmt.Errorf("opening has undefined random values")
+		}
+	}
+	expected, err := apply(pk, in, opening)
+	if err != nil {
+		return err
+	}
+	for j := range expected {
+		if expected[j].Cmp(out[j]) != 0 {
+			return fmt.Errorf("shuffle opening failed")
+		}
+	}
+	return nil
+}
+
+// defined returns true if every ciphertext of the list is defined.
+func defined(list []*big.Int) bool {
+	for _, c := range list {
+		if c == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// challenge returns one bit for each shadow shuffle, computed as the hash of the input,
+// the output and the shadow shuffles.
+func challenge(pk *tcpaillier.PubKey, in, out []*big.Int, shadows [][]*big.Int) []byte {
+	hash := sha256.New()
+	write := func(list []*big.Int) {
+		for _, c := range list {
+			bytes := c.Bytes()
+			_ = binary.Write(hash, binary.BigEndian, uint32(len(bytes)))
+			hash.Write(bytes)
+		}
+	}
+	write([]*big.Int{pk.N, big.NewInt(int64(pk.S))})
+	write(in)
+	write(out)
+	for _, shadow := range shadows {
+		write(shadow)
+	}
+	seed := hash.Sum(nil)
+
+	bits := make([]byte, len(shadows))
+	var block []byte
+	for i := range bits {
+		if i%(8*sha256.Size) == 0 {
+			counter := make([]byte, 4)
+			binary.BigEndian.PutUint32(counter, uint32(i/(8*sha256.Size)))
+			blockHash := sha256.New()
+			blockHash.Write(seed)
+			blockHash.Write(counter)
+			block = blockHash.Sum(nil)
+		}
+		pos := i % (8 * sha256.Size)
+		bits[i] = (block[pos/8] >> uint(pos%8)) & 1
+	}
+	return bits
+}
@@ -0,0 +1,193 @@
+package shuffle_test
+
+import (
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+	"github.com/niclabs/tcpaillier/shuffle"
+)
+
+const k = 2
+const l = 3
+const s = 1
+
+const bitSize = 256
+
+const rounds = shuffle.DefaultRounds
+
+// cheater is a mixer that replaces the first ciphertext of a valid shuffle.
+type cheater struct {
+	shuffle.Shuffler
+}
+
+func (ch *cheater) Mix(pk *tcpaillier.PubKey, in []*big.Int) ([]*big.Int, *shuffle.Proof, error) {
+	out, proof, err := ch.Shuffler.Mix(pk, in)
+	if err != nil {
+		return nil, nil, err
+	}
+	out[0], _, err = pk.Encrypt(big.NewInt(1000))
+	return out, proof, err
+}
+
+// silent is a mixer that shuffles without A proof.
+type silent struct {
+	shuffle.Shuffler
+}
+
+func (sl *silent) Mix(pk *tcpaillier.PubKey, in []*big.Int) ([]*big.Int, *shuffle.Proof, error) {
+	out, _, err := sl.Shuffler.Mix(pk, in)
+	return out, nil, err
+}
+
+func encryptAll(t *testing.T, pk *tcpaillier.PubKey, msgs []int64) []*big.Int {
+	cs := make([]*big.Int, len(msgs))
+	for i, msg := range msgs {
+		c, _, err := pk.Encrypt(big.NewInt(msg))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		cs[i] = c
+	}
+	return cs
+}
+
+func decryptAll(t *testing.T, shares []*tcpaillier.KeyShare, cs []*big.Int) []int64 {
+	msgs := make([]int64, len(cs))
+	for i, c := range cs {
+		decryptShares := make([]*tcpaillier.DecryptionShare, len(shares))
+		for j, share := range shares {
+			ds, err := share.PartialDecrypt(c)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			decryptShares[j] = ds
+		}
+		dec, err := shares[0].CombineShares(decryptShares...)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		msgs[i] = dec.Int64()
+	}
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i] < msgs[j] })
+	return msgs
+}
+
+func TestShuffler_Mix(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	msgs := []int64{1, 2, 3, 4, 5}
+	in := encryptAll(t, pk, msgs)
+	mixer := &shuffle.Shuffler{Rounds: rounds}
+	out, proof, err := mixer.Mix(pk, in)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := proof.Verify(pk, in, out); err != nil {
+		t.Errorf("error verifying proof of shuffle: %v", err)
+	}
+	for i, msg := range decryptAll(t, shares, out) {
+		if msg != msgs[i] {
+			t.Errorf("shuffled messages are %d instead of %d", msg, msgs[i])
+		}
+	}
+
+	// A proof with fewer rounds than the minimum is rejected, even if they are valid.
+	short := &shuffle.Proof{Shadows: proof.Shadows[:1], Openings: proof.Openings[:1]}
+	if err := short.Verify(pk, in, out); err == nil {
+		t.Errorf("proof of shuffle with 1 round should fail")
+	}
+	if _, _, err := (&shuffle.Shuffler{Rounds: 1}).Mix(pk, in); err == nil {
+		t.Errorf("shuffler with 1 round should fail")
+	}
+
+	// Undefined ciphertexts are rejected instead of hashed.
+	shadow := proof.Shadows[0][0]
+	proof.Shadows[0][0] = nil
+	if err := proof.Verify(pk, in, out); err == nil {
+		t.Errorf("proof of shuffle with an undefined shadow should fail")
+	}
+	proof.Shadows[0][0] = shadow
+	if err := proof.Verify(pk, in, append(out[:len(out)-1:len(out)-1], nil)); err == nil {
+		t.Errorf("proof of shuffle with an undefined output should fail")
+	}
+
+	// Swapping two outputs should invalidate the proof.
+	out[0], out[1] = out[1], out[0]
+	if err := proof.Verify(pk, in, out); err == nil {
+		t.Errorf("proof of shuffle should fail with other output")
+	}
+}
+
+func TestRunChain(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	msgs := []int64{10, 20, 30}
+	in := encryptAll(t, pk, msgs)
+	mixers := []shuffle.Mixer{
+		&shuffle.Shuffler{Rounds: rounds},
+		&shuffle.Shuffler{Rounds: rounds},
+		&shuffle.Shuffler{Rounds: rounds},
+	}
+	out, stages, err := shuffle.RunChain(pk, in, mixers...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(stages) != len(mixers) {
+		t.Fatalf("chain has %d stages instead of %d", len(stages), len(mixers))
+	}
+	if err := shuffle.VerifyChain(pk, in, stages); err != nil {
+		t.Errorf("error verifying chain: %v", err)
+	}
+	for i, msg := range decryptAll(t, shares, out) {
+		if msg != msgs[i] {
+			t.Errorf("shuffled messages are %d instead of %d", msg, msgs[i])
+		}
+	}
+
+	mixers[1] = &cheater{shuffle.Shuffler{Rounds: rounds}}
+	_, stages, err = shuffle.RunChain(pk, in, mixers...)
+	mixErr, ok := err.(*shuffle.MixerError)
+	if !ok || mixErr.Index != 1 {
+		t.Errorf("chain should have failed on mixer 1, but error was %v", err)
+	}
+	if len(stages) != 1 {
+		t.Errorf("chain should have completed 1 stage, but it completed %d", len(stages))
+	}
+}
+
+func TestRunChain_undefinedProof(t *testing.T) {
+	_, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	in := encryptAll(t, pk, []int64{10, 20, 30})
+	mixers := []shuffle.Mixer{
+		&shuffle.Shuffler{Rounds: rounds},
+		&silent{shuffle.Shuffler{Rounds: rounds}},
+	}
+	_, stages, err := shuffle.RunChain(pk, in, mixers...)
+	mixErr, ok := err.(*shuffle.MixerError)
+	if !ok || mixErr.Index != 1 {
+		t.Errorf("chain should have failed on mixer 1, but error was %v", err)
+	}
+	if len(stages) != 1 {
+		t.Fatalf("chain should have completed 1 stage, but it completed %d", len(stages))
+	}
+
+	withoutProof := []*shuffle.Stage{{Output: stages[0].Output}}
+	err = shuffle.VerifyChain(pk, in, withoutProof)
+	if mixErr, ok := err.(*shuffle.MixerError); !ok || mixErr.Index != 0 {
+		t.Errorf("chain with A stage without proof should fail on stage 0, but error was %v", err)
+	}
+	undefined := []*shuffle.Stage{stages[0], nil}
+	err = shuffle.VerifyChain(pk, in, undefined)
+	if mixErr, ok := err.(*shuffle.MixerError); !ok || mixErr.Index != 1 {
+		t.Errorf("chain with an undefined stage should fail on stage 1, but error was %v", err)
+	}
+}
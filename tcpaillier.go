@@ -7,6 +7,8 @@ package tcpaillier
 import (
 	"fmt"
 	"math/big"
+
+	"github.com/niclabs/tcpaillier/internal/wipe"
 )
 
 const c = 25
@@ -25,6 +27,14 @@ func (fp *FixedParams) Validate() bool {
 		p1.Cmp(fp.P1) == 0 && q1.Cmp(fp.Q1) == 0
 }
 
+// Destroy overwrites the primes of the params. They cannot be used after this.
+func (fp *FixedParams) Destroy() {
+	wipe.Int(fp.P)
+	wipe.Int(fp.P1)
+	wipe.Int(fp.Q)
+	wipe.Int(fp.Q1)
+}
+
 func (fp *FixedParams) String() string {
 	return fmt.Sprintf("P: %s\nq: %s\np1: %s\nq1: %s\n", fp.P, fp.Q, fp.P1, fp.Q1)
 }
@@ -32,25 +42,40 @@ func (fp *FixedParams) String() string {
 // NewKey returns A list of l keyshares of bitSize bits of length, with A threshold of
 // k and using an s parameter of s in PubKey. It uses randSource
 // as A random source. It also uses A list of fixed params as the primes needed for the scheme.
+// Every intermediate secret value is overwritten before returning, but the params are
+// not, so the caller should destroy them when they are not needed anymore.
 func NewFixedKey(bitSize int, s, l, k uint8, params *FixedParams) (keyShares []*KeyShare, pubKey *PubKey, err error) {
+	var sec secrets
+	defer sec.destroy()
+	return newFixedKey(bitSize, s, l, k, params, &sec)
+}
+
+// newFixedKey is NewFixedKey, but it adds the intermediate secret values to sec instead of
+// overwriting them.
+func newFixedKey(bitSize int, s, l, k uint8, params *FixedParams, sec *secrets) (keyShares []*KeyShare, pubKey *PubKey, err error) {
 	// Parameter checking
 	if bitSize < 64 {
-		err = fmt.Errorf("bitSize should be at least 64 bits, but it is %d", bitSize)
+		err = fmt.Errorf("%w: bitSize should be at least 64 bits, but it is %d", ErrInvalidParams, bitSize)
 		return
 	}
 	if s < 1 {
-		err = fmt.Errorf("s should be at least 1, but it is %d", s)
+		err = fmt.Errorf("%w: s should be at least 1, but it is %d", ErrInvalidParams, s)
+		return
 	}
 	if l <= 1 {
-		err = fmt.Errorf("L should be greater than 1, but it is %d", l)
+		err = fmt.Errorf("%w: L should be greater than 1, but it is %d", ErrInvalidParams, l)
 		return
 	}
 	if k <= 0 {
-		err = fmt.Errorf("K should be greater than 0, but it is %d", k)
+		err = fmt.Errorf("%w: K should be greater than 0, but it is %d", ErrInvalidParams, k)
 		return
 	}
 	if k < (l/2+1) || k > l {
-		err = fmt.Errorf("K should be between %d and %d, but it is %d", (l/2)+1, l, k)
+		err = fmt.Errorf("%w: K should be between %d and %d, but it is %d", ErrInvalidParams, (l/2)+1, l, k)
+		return
+	}
+	if params == nil || !defined(params.P, params.P1, params.Q, params.Q1) {
+		err = fmt.Errorf("%w: fixed params are not defined", ErrInvalidParams)
 		return
 	}
 
@@ -59,12 +84,15 @@ func NewFixedKey(bitSize int, s, l, k uint8, params *FixedParams) (keyShares []*
 
 	n := new(big.Int).Mul(params.P, params.Q)
 	m := new(big.Int).Mul(params.P1, params.Q1)
-	nm := new(big.Int).Mul(n, m)
 	nToS := new(big.Int).Exp(n, bigS, nil)
 	nToSPlusOne := new(big.Int).Exp(n, sPlusOne, nil)
+	// The secret d and its shares are defined modulo n^s*m, so d = 0 mod m and
+	// d = 1 mod n^s.
+	nm := new(big.Int).Mul(nToS, m)
 
-	mInv := new(big.Int).ModInverse(m, n)
+	mInv := new(big.Int).ModInverse(m, nToS)
 	d := new(big.Int).Mul(m, mInv)
+	sec.add(m, nm, mInv, d)
 
 	// Generate polynomial with random coefficients.
 	var poly polynomial
@@ -73,6 +101,7 @@ func NewFixedKey(bitSize int, s, l, k uint8, params *FixedParams) (keyShares []*
 	if err != nil {
 		return
 	}
+	sec.add(poly...)
 
 	// generate Vi with Shoup heuristic
 	var r *big.Int
@@ -85,7 +114,9 @@ func NewFixedKey(bitSize int, s, l, k uint8, params *FixedParams) (keyShares []*
 		if one.Cmp(gcd) == 0 {
 			break
 		}
+		wipe.Int(r)
 	}
+	sec.add(r)
 
 	v := new(big.Int).Mul(r, r)
 	v.Mod(v, nToSPlusOne)
@@ -120,6 +151,10 @@ func NewFixedKey(bitSize int, s, l, k uint8, params *FixedParams) (keyShares []*
 		}
 		deltaSi := new(big.Int).Mul(si, delta)
 		pubKey.Vi[index] = new(big.Int).Exp(v, deltaSi, nToSPlusOne)
+		sec.add(deltaSi)
+	}
+	if err = pubKey.Validate(); err != nil {
+		keyShares, pubKey = nil, nil
 	}
 	return
 }
@@ -127,7 +162,7 @@ func NewFixedKey(bitSize int, s, l, k uint8, params *FixedParams) (keyShares []*
 // NewKey returns A list of l keyshares of bitSize bits of length, with A threshold of
 // k and using an s parameter of s in PubKey. It uses randSource
 // as A random source. If randSource is undefined, it uses crypto/rand
-// reader.
+// reader. The primes generated are overwritten before returning.
 func NewKey(bitSize int, s, l, k uint8) (keyShares []*KeyShare, pubKey *PubKey, err error) {
 
 	pPrimeSize := (bitSize + 1) / 2
@@ -148,5 +183,7 @@ func NewKey(bitSize int, s, l, k uint8) (keyShares []*KeyShare, pubKey *PubKey,
 			break
 		}
 	}
-	return NewFixedKey(bitSize, s, l, k, &FixedParams{p, p1, q, q1,})
+	params := &FixedParams{p, p1, q, q1,}
+	defer params.Destroy()
+	return NewFixedKey(bitSize, s, l, k, params)
 }
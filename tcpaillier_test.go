@@ -2,6 +2,8 @@ package tcpaillier_test
 
 import (
 	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/niclabs/tcpaillier"
 	"math/big"
@@ -552,6 +554,209 @@ func TestPubKey_FixedAdd(t *testing.T) {
 	}
 }
 
+func TestPubKey_DecryptDamgardJurik(t *testing.T) {
+	for _, djS := range []uint8{2, 3} {
+		shares, pk, err := tcpaillier.NewKey(bitSize/2, djS, 3, 2)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		// The message is greater than N, so it needs s > 1.
+		msg := new(big.Int).Exp(pk.N, big.NewInt(int64(djS-1)), nil)
+		msg.Mul(msg, twelve).Add(msg, twentyFive)
+		c, _, err := pk.Encrypt(msg)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		dss := make([]*tcpaillier.DecryptionShare, 2)
+		for i, share := range shares[1:] {
+			if dss[i], err = share.PartialDecrypt(c); err != nil {
+				t.Fatalf("%v", err)
+			}
+		}
+		dec, err := pk.CombineShares(dss...)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if dec.Cmp(msg) != 0 {
+			t.Errorf("decrypted value with s=%d should be %s, but it is %s", djS, msg, dec)
+		}
+	}
+}
+
+func TestPubKey_Validate(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize/2, s, 3, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := pk.Validate(); err != nil {
+		t.Fatalf("generated public key should be valid: %v", err)
+	}
+	b, err := json.Marshal(shares[0])
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var share tcpaillier.KeyShare
+	if err := json.Unmarshal(b, &share); err != nil {
+		t.Fatalf("key share should be decoded: %v", err)
+	}
+	if share.Index != shares[0].Index || share.Si.Cmp(shares[0].Si) != 0 || share.N.Cmp(pk.N) != 0 {
+		t.Errorf("decoded key share is different from the original one")
+	}
+
+	tampers := map[string]func(pk *tcpaillier.PubKey){
+		"missing Vi":       func(pk *tcpaillier.PubKey) { pk.Vi = pk.Vi[1:] },
+		"wrong Delta":      func(pk *tcpaillier.PubKey) { pk.Delta = big.NewInt(7) },
+		"wrong Constant":   func(pk *tcpaillier.PubKey) { pk.Constant = new(big.Int).Add(pk.Constant, big.NewInt(1)) },
+		"V out of range":   func(pk *tcpaillier.PubKey) { pk.V = new(big.Int).Exp(pk.N, big.NewInt(3), nil) },
+		"Vi multiple of N": func(pk *tcpaillier.PubKey) { pk.Vi[0] = new(big.Int).Set(pk.N) },
+		"K greater than L": func(pk *tcpaillier.PubKey) { pk.K = pk.L + 1 },
+	}
+	for name, tamper := range tampers {
+		tampered := *pk
+		tampered.Vi = append([]*big.Int{}, pk.Vi...)
+		tamper(&tampered)
+		if err := tampered.Validate(); err == nil {
+			t.Errorf("public key with %s should be invalid", name)
+		}
+		b, err := json.Marshal(&tampered)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		var decoded tcpaillier.PubKey
+		if err := json.Unmarshal(b, &decoded); err == nil {
+			t.Errorf("public key with %s should not be decoded", name)
+		}
+	}
+}
+
+func TestPubKey_ValidateCiphertext(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize/2, s, 3, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	c, proof, err := pk.EncryptWithProof(twelve)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := pk.ValidateCiphertext(c); err != nil {
+		t.Errorf("encrypted value should be valid: %v", err)
+	}
+	nToSPlusOne := new(big.Int).Exp(pk.N, big.NewInt(s+1), nil)
+	invalid := []struct {
+		name string
+		c    *big.Int
+		err  error
+	}{
+		{"nil", nil, tcpaillier.ErrNilCiphertext},
+		{"zero", big.NewInt(0), tcpaillier.ErrCiphertextOutOfRange},
+		{"N^(s+1)", nToSPlusOne, tcpaillier.ErrCiphertextOutOfRange},
+		{"N", new(big.Int).Set(pk.N), tcpaillier.ErrCiphertextNotCoprime},
+	}
+	for _, test := range invalid {
+		if err := pk.ValidateCiphertext(test.c); !errors.Is(err, test.err) {
+			t.Errorf("%s should fail with %v, but it fails with %v", test.name, test.err, err)
+		}
+		if _, err := pk.Add(c, test.c); !errors.Is(err, test.err) {
+			t.Errorf("adding %s should fail with %v, but it fails with %v", test.name, test.err, err)
+		}
+		if _, err := pk.MultiplyFixed(test.c, twelve, big.NewInt(1)); !errors.Is(err, test.err) {
+			t.Errorf("multiplying %s should fail with %v, but it fails with %v", test.name, test.err, err)
+		}
+		if _, err := shares[0].PartialDecrypt(test.c); !errors.Is(err, test.err) {
+			t.Errorf("decrypting %s should fail with %v, but it fails with %v", test.name, test.err, err)
+		}
+		if err := proof.Verify(pk, test.c); !errors.Is(err, test.err) {
+			t.Errorf("verifying %s should fail with %v, but it fails with %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestKeyShare_Destroy(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize/2, s, 3, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	share := shares[0]
+	words := share.Si.Bits()
+	share.Destroy()
+	for i, word := range words {
+		if word != 0 {
+			t.Errorf("word %d of the secret value was not overwritten", i)
+		}
+	}
+	c, _, err := pk.Encrypt(twelve)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := share.PartialDecrypt(c); err == nil {
+		t.Errorf("destroyed key share should not decrypt values")
+	}
+}
+
+func TestFixedParams_Destroy(t *testing.T) {
+	p, p1, err := tcpaillier.GenerateSafePrimes(64)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	q, q1, err := tcpaillier.GenerateSafePrimes(64)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	params := &tcpaillier.FixedParams{P: p, P1: p1, Q: q, Q1: q1}
+	words := [][]big.Word{p.Bits(), p1.Bits(), q.Bits(), q1.Bits()}
+	params.Destroy()
+	for i, primeWords := range words {
+		for _, word := range primeWords {
+			if word != 0 {
+				t.Errorf("prime %d was not overwritten", i)
+				break
+			}
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	shares, pk, err := tcpaillier.NewKey(bitSize/2, s, 3, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	c, proof, err := pk.EncryptWithProof(twelve)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	ds, err := shares[0].PartialDecrypt(c)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	_, err = pk.CombineShares(ds)
+	var insufficient *tcpaillier.InsufficientSharesError
+	if !errors.As(err, &insufficient) {
+		t.Errorf("combining one share should fail with InsufficientSharesError, but it fails with %v", err)
+	} else if insufficient.Have != 1 || insufficient.Need != 2 {
+		t.Errorf("error should have Have=1 and Need=2, but it has Have=%d and Need=%d", insufficient.Have, insufficient.Need)
+	}
+	c2, _, err := pk.Encrypt(twelve)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := proof.Verify(pk, c2); !errors.Is(err, tcpaillier.ErrInvalidProof) {
+		t.Errorf("verifying A proof for another value should fail with %v, but it fails with %v", tcpaillier.ErrInvalidProof, err)
+	}
+	if err := (&tcpaillier.EncryptZK{}).Verify(pk, c); !errors.Is(err, tcpaillier.ErrInvalidProof) {
+		t.Errorf("verifying an incomplete proof should fail with %v, but it fails with %v", tcpaillier.ErrInvalidProof, err)
+	}
+	if _, _, err := tcpaillier.NewKey(bitSize/2, 0, 3, 2); !errors.Is(err, tcpaillier.ErrInvalidParams) {
+		t.Errorf("creating A key with s=0 should fail with %v, but it fails with %v", tcpaillier.ErrInvalidParams, err)
+	}
+	if _, err := pk.CombineShares(ds, ds); !errors.Is(err, tcpaillier.ErrRepeatedShare) {
+		t.Errorf("combining A repeated share should fail with %v, but it fails with %v", tcpaillier.ErrRepeatedShare, err)
+	}
+	shares[1].Destroy()
+	if _, err := shares[1].PartialDecrypt(c); !errors.Is(err, tcpaillier.ErrKeyShareDestroyed) {
+		t.Errorf("decrypting with A destroyed share should fail with %v, but it fails with %v", tcpaillier.ErrKeyShareDestroyed, err)
+	}
+}
+
 func ExamplePubKey_Add() {
 	// First, we create the shares with the parameters provided.
 	shares, pk, err := tcpaillier.NewKey(512, 1, 5, 3)
@@ -3,8 +3,11 @@ package tcpaillier
 import (
 	"crypto"
 	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"math/big"
+
+	"github.com/niclabs/tcpaillier/internal/wipe"
 )
 
 // KeyShare represents A share of the private key
@@ -15,24 +18,106 @@ type KeyShare struct {
 	Si    *big.Int
 }
 
+// ShareSigner represents A holder of A key share that decrypts values partially without
+// revealing the secret value of the share. KeyShare complies with this interface, and
+// other implementations can keep the secret value out of the process memory.
+type ShareSigner interface {
+	// PublicKey returns the public key the key share belongs to.
+	PublicKey() *PubKey
+	// ShareIndex returns the index of the key share.
+	ShareIndex() uint8
+	// PartialDecrypt decrypts partially the encrypted value.
+	PartialDecrypt(c *big.Int) (*DecryptionShare, error)
+	// PartialDecryptProof returns A proof of the partial decryption of the encrypted value.
+	PartialDecryptProof(c *big.Int, ds *DecryptionShare) (*DecryptShareZK, error)
+}
+
+// MarshalJSON encodes A key share with the fingerprint of its public key.
+func (ts *KeyShare) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		*plainPubKey
+		Fingerprint Fingerprint
+		Index       uint8
+		Si          *big.Int
+	}{
+		plainPubKey: (*plainPubKey)(ts.PubKey),
+		Fingerprint: ts.Fingerprint(),
+		Index:       ts.Index,
+		Si:          ts.Si,
+	})
+}
+
+// UnmarshalJSON decodes A key share and validates its public key. It is needed because
+// the key share would use the method of its public key otherwise. If the key share has
+// A fingerprint, it must be the one of its public key.
+func (ts *KeyShare) UnmarshalJSON(b []byte) error {
+	var pk PubKey
+	if err := pk.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	var secret struct {
+		Fingerprint Fingerprint
+		Index       uint8
+		Si          *big.Int
+	}
+	if err := json.Unmarshal(b, &secret); err != nil {
+		return err
+	}
+	if err := pk.checkFingerprint("key share", secret.Fingerprint); err != nil {
+		return err
+	}
+	if secret.Index < 1 || secret.Index > pk.L {
+		return fmt.Errorf("key share index should be between 1 and %d, but it is %d", pk.L, secret.Index)
+	}
+	if secret.Si == nil || secret.Si.Sign() < 0 {
+		return fmt.Errorf("key share secret value should be a non negative integer")
+	}
+	ts.PubKey = &pk
+	ts.Index = secret.Index
+	ts.Si = secret.Si
+	return nil
+}
+
+// PublicKey returns the public key of the key share.
+func (ts *KeyShare) PublicKey() *PubKey {
+	return ts.PubKey
+}
+
+// ShareIndex returns the index of the key share.
+func (ts *KeyShare) ShareIndex() uint8 {
+	return ts.Index
+}
+
+// Destroy overwrites the secret value of the key share. The key share cannot be used
+// after this.
+func (ts *KeyShare) Destroy() {
+	wipe.Int(ts.Si)
+	ts.Si = nil
+}
+
 // PartialDecrypt decrypts the encrypted value partially, using only one
 // keyShare.
 func (ts *KeyShare) PartialDecrypt(c *big.Int) (ds *DecryptionShare, err error) {
+	if ts.Si == nil {
+		err = ErrKeyShareDestroyed
+		return
+	}
 	cache := ts.Cache()
 	nToSPlusOne := cache.NToSPlusOne
-	if c.Cmp(nToSPlusOne) >= 0 || c.Cmp(zero) < 0 {
-		err = fmt.Errorf("CAlpha must be between 0 (inclusive) and N^(s+1) (exclusive)")
+	if err = ts.validateCiphertext("c", c); err != nil {
 		return
 	}
 
 	DeltaSi2 := new(big.Int)
 	DeltaSi2.Mul(two, ts.Delta).Mul(DeltaSi2, ts.Si)
+	defer wipe.Int(DeltaSi2)
 
 	pd := new(big.Int).Exp(c, DeltaSi2, nToSPlusOne)
 
 	ds = &DecryptionShare{
-		Index: ts.Index,
-		Ci:    pd,
+		Fingerprint: ts.Fingerprint(),
+		Index:       ts.Index,
+		Ci:          pd,
 	}
 
 	return
@@ -50,7 +135,21 @@ func (ts *KeyShare) PartialDecryptWithProof(c *big.Int) (ds *DecryptionShare, zk
 	return
 }
 
+// PartialDecryptProof returns A ZKProof of the partial decryption ds of the encrypted value c.
 func (ts *KeyShare) PartialDecryptProof(c *big.Int, ds *DecryptionShare) (zk *DecryptShareZK, err error) {
+	if ts.Si == nil {
+		err = ErrKeyShareDestroyed
+		return
+	}
+	if err = ts.validateCiphertext("c", c); err != nil {
+		return
+	}
+	if err = ts.validateCiphertext("share", ds.Ci); err != nil {
+		return
+	}
+	if err = ts.checkFingerprint("share", ds.Fingerprint); err != nil {
+		return
+	}
 
 	cache := ts.Cache()
 	nToSPlusOne := cache.NToSPlusOne
@@ -60,6 +159,7 @@ func (ts *KeyShare) PartialDecryptProof(c *big.Int, ds *DecryptionShare) (zk *De
 	if err != nil {
 		return
 	}
+	defer wipe.Int(r)
 	cTo4 := new(big.Int).Exp(c, big.NewInt(4), nToSPlusOne)
 	v := ts.V
 	vi := ts.Vi[ts.Index-1]
@@ -80,6 +180,7 @@ func (ts *KeyShare) PartialDecryptProof(c *big.Int, ds *DecryptionShare) (zk *De
 
 	eSiDelta := new(big.Int)
 	eSiDelta.Mul(ts.Si, e).Mul(eSiDelta, ts.Delta)
+	defer wipe.Int(eSiDelta)
 	z := new(big.Int).Add(eSiDelta, r)
 
 	zk = &DecryptShareZK{
@@ -87,6 +188,8 @@ func (ts *KeyShare) PartialDecryptProof(c *big.Int, ds *DecryptionShare) (zk *De
 		E:  e,
 		V:  v,
 		Z:  z,
+		A:  a,
+		B:  b,
 	}
 	return
 }
@@ -19,13 +19,18 @@ type MulZK struct {
 }
 
 // DecryptShareZK represents A ZKProof related to the decryption
-// of an encrypted share by A constant.
+// of an encrypted share by A constant. A and B are the commitments
+// of the proof, and they are only needed for batch verification.
 type DecryptShareZK struct {
 	V, Vi, Z, E *big.Int
+	A, B        *big.Int
 }
 
 // Verify verifies the Encryption ZKProof.
 func (zk *EncryptZK) Verify(pk *PubKey, vals ...interface{}) error {
+	if zk == nil || !defined(zk.B, zk.W, zk.Z) {
+		return fmt.Errorf("%w: proof is incomplete", ErrInvalidProof)
+	}
 
 	if len(vals) != 1 {
 		return fmt.Errorf("the extra value for verification should be only the encrypted value")
@@ -36,17 +41,28 @@ func (zk *EncryptZK) Verify(pk *PubKey, vals ...interface{}) error {
 		return fmt.Errorf("cannot cast first verification value as A *big.Int")
 	}
 
+	if err := pk.validateCiphertext("c", c); err != nil {
+		return err
+	}
+
+	return zk.verify(pk, c, encryptChallenge(c, zk.B))
+}
+
+// encryptChallenge returns the challenge of an Encryption ZKProof of c with commitment b.
+func encryptChallenge(c, b *big.Int) *big.Int {
+	hash := sha256.New()
+	hash.Write(c.Bytes())
+	hash.Write(b.Bytes())
+	return new(big.Int).SetBytes(hash.Sum(nil))
+}
+
+// verify checks the Encryption ZKProof of c with the challenge e.
+func (zk *EncryptZK) verify(pk *PubKey, c, e *big.Int) error {
 	cache := pk.Cache()
 	nPlusOne := cache.NPlusOne
 	nToSPlusOne := cache.NToSPlusOne
 	nToS := cache.NToS
 
-	hash := sha256.New()
-	hash.Write(c.Bytes())
-	hash.Write(zk.B.Bytes())
-	eHash := hash.Sum(nil)
-	e := new(big.Int).SetBytes(eHash)
-
 	// (n+1)^W % n^(s+1)
 	nPlusOneToW := new(big.Int).Exp(nPlusOne, zk.W, nToSPlusOne)
 	// Z^n % n^(s+1)
@@ -62,13 +78,16 @@ func (zk *EncryptZK) Verify(pk *PubKey, vals ...interface{}) error {
 	right.Mul(zk.B, cToE).Mod(right, nToSPlusOne)
 
 	if left.Cmp(right) != 0 {
-		return fmt.Errorf("zkproof failed")
+		return ErrInvalidProof
 	}
 	return nil
 }
 
 // Verify verifies the Multiplication ZKProof.
 func (zk *MulZK) Verify(pk *PubKey, vals ...interface{}) error {
+	if zk == nil || !defined(zk.CAlpha, zk.A, zk.B, zk.W, zk.Y, zk.Z) {
+		return fmt.Errorf("%w: proof is incomplete", ErrInvalidProof)
+	}
 
 	if len(vals) != 2 {
 		return fmt.Errorf("the extra values for verification should be the result and the encrypted value")
@@ -85,6 +104,13 @@ func (zk *MulZK) Verify(pk *PubKey, vals ...interface{}) error {
 	}
 
 
+	if err := pk.validateCiphertext("d", d); err != nil {
+		return err
+	}
+	if err := pk.validateCiphertext("ca", ca); err != nil {
+		return err
+	}
+
 	cache := pk.Cache()
 	nPlusOne := cache.NPlusOne
 	nToSPlusOne := cache.NToSPlusOne
@@ -115,7 +141,7 @@ func (zk *MulZK) Verify(pk *PubKey, vals ...interface{}) error {
 	zk2.Mul(cToE, zk.B).Mod(zk2, nToSPlusOne)
 
 	if zk1.Cmp(zk2) != 0 {
-		return fmt.Errorf("zkproof failed")
+		return ErrInvalidProof
 	}
 
 	// ca^W % n^(s+1)
@@ -133,14 +159,16 @@ func (zk *MulZK) Verify(pk *PubKey, vals ...interface{}) error {
 	zk4.Mod(zk4, nToSPlusOne)
 
 	if zk3.Cmp(zk4) != 0 {
-		return fmt.Errorf("zkproof failed")
+		return ErrInvalidProof
 	}
 	return nil
 }
 
 // Verify verifies the ZKProof inside A DecryptionShare
 func (zk *DecryptShareZK) Verify(pk *PubKey, vals ...interface{}) error {
-
+	if zk == nil || !defined(zk.V, zk.Vi, zk.Z, zk.E) {
+		return fmt.Errorf("%w: proof is incomplete", ErrInvalidProof)
+	}
 
 	if len(vals) != 2 {
 		return fmt.Errorf("the extra values for verification should be only the encrypted value and the decrypted share")
@@ -152,10 +180,27 @@ func (zk *DecryptShareZK) Verify(pk *PubKey, vals ...interface{}) error {
 	}
 
 	ds, ok := vals[1].(*DecryptionShare)
-	if !ok {
+	if !ok || ds == nil {
 		return fmt.Errorf("cannot cast second verification value as A decryptionShare")
 	}
 
+	if err := pk.validateCiphertext("c", c); err != nil {
+		return err
+	}
+	if err := pk.validateCiphertext("share", ds.Ci); err != nil {
+		return err
+	}
+	if err := pk.checkFingerprint("share", ds.Fingerprint); err != nil {
+		return err
+	}
+
+	if ds.Index < 1 || ds.Index > pk.L {
+		return fmt.Errorf("%w: share index should be between 1 and %d, but it is %d", ErrInvalidProof, pk.L, ds.Index)
+	}
+	if zk.V.Cmp(pk.V) != 0 || zk.Vi.Cmp(pk.Vi[ds.Index-1]) != 0 {
+		return fmt.Errorf("%w: verification values are not the ones of the public key", ErrInvalidProof)
+	}
+
 	cache := pk.Cache()
 	nToSPlusOne := cache.NToSPlusOne
 	cTo4 := new(big.Int).Exp(c, big.NewInt(4), nToSPlusOne)
@@ -182,7 +227,249 @@ func (zk *DecryptShareZK) Verify(pk *PubKey, vals ...interface{}) error {
 	e := new(big.Int).SetBytes(eBytes)
 
 	if e.Cmp(zk.E) != 0 {
-		return fmt.Errorf("zkproof failed")
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// EqualityZK represents A ZKProof that two encrypted values are encryptions
+// of the same message. Both values can be encrypted with the same public key,
+// or with different public keys.
+type EqualityZK struct {
+	A1, A2, W, Z1, Z2 *big.Int
+}
+
+// Verify verifies the Equality ZKProof. The extra values are the two encrypted values
+// and, if the second one was encrypted with A different key, the public key of the second
+// value.
+func (zk *EqualityZK) Verify(pk *PubKey, vals ...interface{}) error {
+	if zk == nil || !defined(zk.A1, zk.A2, zk.W, zk.Z1, zk.Z2) {
+		return fmt.Errorf("%w: proof is incomplete", ErrInvalidProof)
+	}
+
+	if len(vals) != 2 && len(vals) != 3 {
+		return fmt.Errorf("the extra values for verification should be the two encrypted values and optionally the public key of the second one")
+	}
+
+	c1, ok := vals[0].(*big.Int)
+	if !ok {
+		return fmt.Errorf("cannot cast first verification value as A *big.Int")
+	}
+
+	c2, ok := vals[1].(*big.Int)
+	if !ok {
+		return fmt.Errorf("cannot cast second verification value as A *big.Int")
+	}
+
+	other := pk
+	if len(vals) == 3 {
+		other, ok = vals[2].(*PubKey)
+		if !ok {
+			return fmt.Errorf("cannot cast third verification value as A *PubKey")
+		}
+	}
+
+	if err := pk.validateCiphertext("c1", c1); err != nil {
+		return err
+	}
+	if err := other.validateCiphertext("c2", c2); err != nil {
+		return err
+	}
+
+	// W is not reduced, so it could be chosen to hold for different moduli without
+	// proving anything. Honest responses are e*message + x, with x of equalityBits bits.
+	if zk.W.Sign() < 0 || zk.W.BitLen() > equalityBits(pk, other)+1 {
+		return ErrInvalidProof
+	}
+
+	e := equalityChallenge(pk, other, c1, c2, zk.A1, zk.A2)
+
+	if !equalityHolds(pk, c1, zk.A1, zk.W, zk.Z1, e) || !equalityHolds(other, c2, zk.A2, zk.W, zk.Z2, e) {
+		return ErrInvalidProof
 	}
 	return nil
 }
+
+// minNToS returns the smaller N^s of both public keys.
+func minNToS(pk1, pk2 *PubKey) *big.Int {
+	nToS1, nToS2 := pk1.Cache().NToS, pk2.Cache().NToS
+	if nToS2.Cmp(nToS1) < 0 {
+		return nToS2
+	}
+	return nToS1
+}
+
+// equalityBits returns the bit length of the random value x used by an Equality ZKProof,
+// that is big enough to hide e*message statistically.
+func equalityBits(pk1, pk2 *PubKey) int {
+	return minNToS(pk1, pk2).BitLen() + 2*sha256.Size*8
+}
+
+// equalityChallenge returns the challenge of an Equality ZKProof.
+func equalityChallenge(pk1, pk2 *PubKey, c1, c2, a1, a2 *big.Int) *big.Int {
+	hash := sha256.New()
+	hash.Write(pk1.N.Bytes())
+	hash.Write([]byte{pk1.S})
+	hash.Write(pk2.N.Bytes())
+	hash.Write([]byte{pk2.S})
+	hash.Write(c1.Bytes())
+	hash.Write(c2.Bytes())
+	hash.Write(a1.Bytes())
+	hash.Write(a2.Bytes())
+	return new(big.Int).SetBytes(hash.Sum(nil))
+}
+
+// equalityHolds checks that (n+1)^W * Z^(n^s) = A * c^E % n^(s+1)
+// for one of the encrypted values of an Equality ZKProof.
+func equalityHolds(pk *PubKey, c, a, w, z, e *big.Int) bool {
+	cache := pk.Cache()
+	nPlusOne := cache.NPlusOne
+	nToSPlusOne := cache.NToSPlusOne
+	nToS := cache.NToS
+
+	// (n+1)^W * Z^(n^s) % n^(s+1)
+	nPlusOneToW := new(big.Int).Exp(nPlusOne, w, nToSPlusOne)
+	zToNToS := new(big.Int).Exp(z, nToS, nToSPlusOne)
+	left := new(big.Int)
+	left.Mul(nPlusOneToW, zToNToS).Mod(left, nToSPlusOne)
+
+	// A * c^E % n^(s+1)
+	cToE := new(big.Int).Exp(c, e, nToSPlusOne)
+	right := new(big.Int)
+	right.Mul(a, cToE).Mod(right, nToSPlusOne)
+
+	return left.Cmp(right) == 0
+}
+
+// ReRandZK represents A ZKProof that A value is A rerandomization of
+// another encrypted value, so both of them encrypt the same message.
+type ReRandZK struct {
+	A, Z *big.Int
+}
+
+// Verify verifies the Rerandomization ZKProof. The extra values are the
+// original encrypted value and its rerandomization.
+func (zk *ReRandZK) Verify(pk *PubKey, vals ...interface{}) error {
+	if zk == nil || !defined(zk.A, zk.Z) {
+		return fmt.Errorf("%w: proof is incomplete", ErrInvalidProof)
+	}
+
+	if len(vals) != 2 {
+		return fmt.Errorf("the extra values for verification should be the encrypted value and its rerandomization")
+	}
+
+	c, ok := vals[0].(*big.Int)
+	if !ok {
+		return fmt.Errorf("cannot cast first verification value as A *big.Int")
+	}
+
+	reRand, ok := vals[1].(*big.Int)
+	if !ok {
+		return fmt.Errorf("cannot cast second verification value as A *big.Int")
+	}
+
+	if err := pk.validateCiphertext("c", c); err != nil {
+		return err
+	}
+	if err := pk.validateCiphertext("reRand", reRand); err != nil {
+		return err
+	}
+
+	cache := pk.Cache()
+	nToSPlusOne := cache.NToSPlusOne
+	nToS := cache.NToS
+
+	hash := sha256.New()
+	hash.Write(c.Bytes())
+	hash.Write(reRand.Bytes())
+	hash.Write(zk.A.Bytes())
+	e := new(big.Int).SetBytes(hash.Sum(nil))
+
+	// Z^(n^s) * c^E % n^(s+1)
+	zToNToS := new(big.Int).Exp(zk.Z, nToS, nToSPlusOne)
+	cToE := new(big.Int).Exp(c, e, nToSPlusOne)
+	left := new(big.Int)
+	left.Mul(zToNToS, cToE).Mod(left, nToSPlusOne)
+
+	// A * reRand^E % n^(s+1)
+	reRandToE := new(big.Int).Exp(reRand, e, nToSPlusOne)
+	right := new(big.Int)
+	right.Mul(zk.A, reRandToE).Mod(right, nToSPlusOne)
+
+	if left.Cmp(right) != 0 {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// BitZK represents A ZKProof that an encrypted value is an encryption of 0 or 1.
+// It is an OR composition of two proofs of N^s-th residuosity, one of them simulated.
+type BitZK struct {
+	A0, A1, E0, E1, Z0, Z1 *big.Int
+}
+
+// Verify verifies the Bit ZKProof. The extra value is the encrypted value.
+func (zk *BitZK) Verify(pk *PubKey, vals ...interface{}) error {
+	if zk == nil || !defined(zk.A0, zk.A1, zk.E0, zk.E1, zk.Z0, zk.Z1) {
+		return fmt.Errorf("%w: proof is incomplete", ErrInvalidProof)
+	}
+
+	if len(vals) != 1 {
+		return fmt.Errorf("the extra value for verification should be only the encrypted value")
+	}
+
+	c, ok := vals[0].(*big.Int)
+	if !ok {
+		return fmt.Errorf("cannot cast first verification value as A *big.Int")
+	}
+
+	for _, ei := range []*big.Int{zk.E0, zk.E1} {
+		if ei.Sign() < 0 || ei.Cmp(bitChallengeMod) >= 0 {
+			return ErrInvalidProof
+		}
+	}
+
+	if err := pk.validateCiphertext("c", c); err != nil {
+		return err
+	}
+
+	us := pk.bitResidues(c)
+
+	e := bitChallenge(c, zk.A0, zk.A1)
+	eSum := new(big.Int).Add(zk.E0, zk.E1)
+	eSum.Mod(eSum, bitChallengeMod)
+	if eSum.Cmp(e) != 0 {
+		return ErrInvalidProof
+	}
+
+	cache := pk.Cache()
+	nToSPlusOne := cache.NToSPlusOne
+	nToS := cache.NToS
+
+	as := []*big.Int{zk.A0, zk.A1}
+	es := []*big.Int{zk.E0, zk.E1}
+	zs := []*big.Int{zk.Z0, zk.Z1}
+	for i := range us {
+		// Z^(n^s) = A * u^E % n^(s+1)
+		left := new(big.Int).Exp(zs[i], nToS, nToSPlusOne)
+		uToE := new(big.Int).Exp(us[i], es[i], nToSPlusOne)
+		right := new(big.Int)
+		right.Mul(as[i], uToE).Mod(right, nToSPlusOne)
+		if left.Cmp(right) != 0 {
+			return ErrInvalidProof
+		}
+	}
+	return nil
+}
+
+// bitChallengeMod is the modulus of the challenges of A Bit ZKProof.
+var bitChallengeMod = new(big.Int).Lsh(one, sha256.Size*8)
+
+// bitChallenge returns the challenge of A Bit ZKProof.
+func bitChallenge(c, a0, a1 *big.Int) *big.Int {
+	hash := sha256.New()
+	hash.Write(c.Bytes())
+	hash.Write(a0.Bytes())
+	hash.Write(a1.Bytes())
+	return new(big.Int).SetBytes(hash.Sum(nil))
+}
@@ -0,0 +1,199 @@
+package tcpaillier_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/niclabs/tcpaillier"
+)
+
+func TestPubKey_EqualityProof(t *testing.T) {
+	_, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	c1, r1, err := pk.Encrypt(twelve)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	c2, r2, err := pk.Encrypt(twelve)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	zk, err := pk.EqualityProof(twelve, c1, r1, c2, r2)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if err := zk.Verify(pk, c1, c2); err != nil {
+		t.Errorf("error verifying equality ZKProof: %v", err)
+		return
+	}
+	c3, r3, err := pk.Encrypt(twentyFive)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if err := zk.Verify(pk, c1, c3); err == nil {
+		t.Errorf("equality ZKProof should fail with other encrypted value")
+		return
+	}
+	zk, err = pk.EqualityProof(twelve, c1, r1, c3, r3)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if err := zk.Verify(pk, c1, c3); err == nil {
+		t.Errorf("equality ZKProof of different messages should fail")
+		return
+	}
+}
+
+func TestPubKey_CrossEqualityProof(t *testing.T) {
+	_, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	_, other, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	// The same modulus with a bigger S
+	otherS := &tcpaillier.PubKey{N: pk.N, S: s + 1}
+
+	c1, r1, err := pk.Encrypt(threeHundred)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	for _, otherPK := range []*tcpaillier.PubKey{other, otherS} {
+		c2, r2, err := otherPK.Encrypt(threeHundred)
+		if err != nil {
+			t.Errorf("%v", err)
+			return
+		}
+		zk, err := pk.CrossEqualityProof(otherPK, threeHundred, c1, r1, c2, r2)
+		if err != nil {
+			t.Errorf("%v", err)
+			return
+		}
+		if err := zk.Verify(pk, c1, c2, otherPK); err != nil {
+			t.Errorf("error verifying cross equality ZKProof: %v", err)
+			return
+		}
+		if err := zk.Verify(pk, c1, c2); err == nil {
+			t.Errorf("cross equality ZKProof should fail with the wrong key")
+			return
+		}
+		// A response with A multiple of N^(s+1) added holds for both keys with the same
+		// modulus, so it must be rejected by its size.
+		long := *zk
+		long.W = new(big.Int).Lsh(otherPK.Cache().NToSPlusOne, 600)
+		long.W.Add(long.W, zk.W)
+		if err := long.Verify(pk, c1, c2, otherPK); err == nil {
+			t.Errorf("cross equality ZKProof with A response too long should fail")
+			return
+		}
+		wrong := new(big.Int).Add(threeHundred, big.NewInt(1))
+		zk, err = pk.CrossEqualityProof(otherPK, wrong, c1, r1, c2, r2)
+		if err != nil {
+			t.Errorf("%v", err)
+			return
+		}
+		if err := zk.Verify(pk, c1, c2, otherPK); err == nil {
+			t.Errorf("cross equality ZKProof with a wrong message should fail")
+			return
+		}
+	}
+}
+
+func TestPubKey_ReRandWithProof(t *testing.T) {
+	_, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	encrypted, _, err := pk.Encrypt(twelve)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	reRand, zk, err := pk.ReRandWithProof(encrypted)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if reRand.Cmp(encrypted) == 0 {
+		t.Errorf("rerandomized value is equal to the original one")
+		return
+	}
+
+	// The proof should be verified after a serialization round trip.
+	b, err := json.Marshal(zk)
+	if err != nil {
+		t.Errorf("cannot marshal rerandomization ZKProof: %v", err)
+		return
+	}
+	var decoded tcpaillier.ReRandZK
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Errorf("cannot unmarshal rerandomization ZKProof: %v", err)
+		return
+	}
+	if err := decoded.Verify(pk, encrypted, reRand); err != nil {
+		t.Errorf("error verifying rerandomization ZKProof: %v", err)
+		return
+	}
+
+	other, _, err := pk.Encrypt(twelve)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if err := zk.Verify(pk, other, reRand); err == nil {
+		t.Errorf("rerandomization ZKProof should fail with other encrypted value")
+		return
+	}
+}
+
+func TestPubKey_EncryptBitWithProof(t *testing.T) {
+	_, pk, err := tcpaillier.NewKey(bitSize, s, l, k)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	for _, bit := range []int64{0, 1} {
+		c, zk, err := pk.EncryptBitWithProof(big.NewInt(bit))
+		if err != nil {
+			t.Errorf("%v", err)
+			return
+		}
+		if err := zk.Verify(pk, c); err != nil {
+			t.Errorf("error verifying bit ZKProof of %d: %v", bit, err)
+			return
+		}
+	}
+	if _, _, err := pk.EncryptBitWithProof(big.NewInt(2)); err == nil {
+		t.Errorf("encryption of 2 as a bit should fail")
+		return
+	}
+	// A proof of a bit should not verify a value that is not 0 or 1.
+	c, r, err := pk.Encrypt(big.NewInt(2))
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	zk, err := pk.BitProof(big.NewInt(1), c, r)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if err := zk.Verify(pk, c); err == nil {
+		t.Errorf("bit ZKProof of an encryption of 2 should fail")
+	}
+}